@@ -0,0 +1,72 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package cmd
+
+import (
+	"github.com/rbtr/pachinko/internal/config"
+	"github.com/rbtr/pachinko/internal/pipeline"
+	"github.com/rbtr/pachinko/internal/plan"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// apply represents the apply command.
+var apply = &cobra.Command{
+	Use:   "apply [plan file]",
+	Short: "Apply a plan written by sort --plan.",
+	Long: `
+Use this command to take exactly the actions in a plan written by
+"pachinko sort --plan".
+  $ pachinko sort --plan plan.json
+  $ pachinko apply plan.json
+
+The plan is a JSON file that can be reviewed and edited before it is applied.
+Actions can be removed, and their destinations changed.
+
+Before anything is changed, every action is checked: the sources must still
+exist and the destinations must be free. If any action fails the check,
+nothing is applied.
+
+The items in the plan are sent to the configured outputs, without running
+the inputs or processors again.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.SetLevel(log.TraceLevel)
+		sortConf, err := config.LoadSort(rootCtx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sortConf.Validate(); err != nil {
+			log.Fatal(err)
+		}
+
+		pl, err := plan.Read(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if errs := pl.Validate(); len(errs) > 0 {
+			for _, err := range errs {
+				log.Error(err)
+			}
+			log.Fatalf("%d actions in %s can't be applied", len(errs), args[0])
+		}
+
+		p := pipeline.NewPipeline()
+		if err := sortConf.ConfigureApply(p, pl); err != nil {
+			log.Fatal(err)
+		}
+		if err := p.Run(rootCtx); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	root.AddCommand(apply)
+}
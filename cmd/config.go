@@ -0,0 +1,94 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/rbtr/pachinko/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configCmd represents the config command.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the sort config.",
+}
+
+// configValidate represents the config validate command.
+var configValidate = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the sort config for mistakes.",
+	Long: `
+Use this command to check the sort config without running anything.
+  $ pachinko config validate --config /path/to/config
+
+Every plugin must have a name that is registered, and only the options
+that the plugin has. Misspelled names and options are reported with the
+closest match:
+  outputs[0] (path-mover): unknown option create_dirs, did you mean create-dirs?
+The top level of the config is checked too:
+  config: unknown option ouptuts, did you mean outputs?
+
+Plugins can also check their options, for missing API keys or source
+directories that don't exist. The plugins aren't initialized, so nothing
+is connected to.
+
+All of the mistakes are reported, and the command fails if there are any.
+sort and apply check the config in the same way, and fail at the first.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.SetLevel(log.TraceLevel)
+		sortConf, err := config.LoadSort(rootCtx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sortConf.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		errs := sortConf.Check()
+		for _, err := range errs {
+			log.Error(err)
+		}
+		if len(errs) > 0 {
+			log.Fatalf("%s is invalid", viper.ConfigFileUsed())
+		}
+		log.Infof("%s is valid", viper.ConfigFileUsed())
+	},
+}
+
+// configSchema represents the config schema command.
+var configSchema = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the sort config.",
+	Long: `
+Use this command to generate a JSON Schema of the sort config, with the
+options of every compiled plug-in, their descriptions, and their defaults.
+  $ pachinko config schema > pachinko.schema.json
+
+Editors can use the schema to validate and autocomplete configs. For
+editors that use the yaml language server, add a modeline to the config:
+  # yaml-language-server: $schema=./pachinko.schema.json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.Schema()); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidate)
+	configCmd.AddCommand(configSchema)
+	root.AddCommand(configCmd)
+}
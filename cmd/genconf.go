@@ -17,7 +17,6 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v2"
 )
 
 // genconf represents the config command.
@@ -35,7 +34,9 @@ Note: the generated config is always in alphabetical order of keys.
 	  If order matters to pipeline plug-in execution, it will need
 	  to be reordered after generation.
   
-The config can be output as either yaml (default) or toml.
+The config can be output as either yaml (default) or toml. The yaml
+config is commented with the description of each option, and the
+values it can be.
   $ pachinko genconf -o toml > config.toml
   
 To only generate stubs for a subset of plug-ins, pass the plug-in
@@ -75,9 +76,11 @@ and the pachinko pipeline.
 				log.Fatal(err)
 			}
 		default:
-			if err := yaml.NewEncoder(buf).Encode(out); err != nil {
+			b, err := config.Schema().YAML(out)
+			if err != nil {
 				log.Fatal(err)
 			}
+			buf.Write(b)
 		}
 		fmt.Println(buf)
 	},
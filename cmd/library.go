@@ -0,0 +1,138 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rbtr/pachinko/internal/config"
+	internallibrary "github.com/rbtr/pachinko/internal/library"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// library represents the library query command.
+var library = &cobra.Command{
+	Use:   "library",
+	Short: "Query the library index.",
+	Long: `
+Use the subcommands of this command to query the library index database
+written by the "library" output.
+  $ pachinko library shows
+  $ pachinko library missing
+  $ pachinko library duplicates
+`,
+}
+
+var libraryShows = &cobra.Command{
+	Use:   "shows",
+	Short: "List the shows and their seasons in the library.",
+	Run: func(cmd *cobra.Command, args []string) {
+		withLibrary(func(db *internallibrary.DB, w *tabwriter.Writer) error {
+			shows, err := db.Shows()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, "SHOW\tSEASON\tEPISODES")
+			for _, show := range shows {
+				for _, s := range show.Seasons {
+					fmt.Fprintf(w, "%s\t%d\t%d\n", show.Name, s.Number, len(s.Episodes))
+				}
+			}
+			return nil
+		})
+	},
+}
+
+var libraryMissing = &cobra.Command{
+	Use:   "missing",
+	Short: "List the episodes missing from each season in the library.",
+	Long: `
+List the episodes missing from each season in the library, between the first
+episode and the highest numbered episode that is in the library.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		withLibrary(func(db *internallibrary.DB, w *tabwriter.Writer) error {
+			shows, err := db.Shows()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, "SHOW\tSEASON\tMISSING")
+			for _, show := range shows {
+				for _, s := range show.Seasons {
+					missing := s.Missing()
+					if len(missing) == 0 {
+						continue
+					}
+					episodes := make([]string, len(missing))
+					for i, e := range missing {
+						episodes[i] = fmt.Sprint(e)
+					}
+					fmt.Fprintf(w, "%s\t%d\t%s\n", show.Name, s.Number, strings.Join(episodes, ","))
+				}
+			}
+			return nil
+		})
+	},
+}
+
+var libraryDuplicates = &cobra.Command{
+	Use:   "duplicates",
+	Short: "List the movies that are in the library more than once.",
+	Run: func(cmd *cobra.Command, args []string) {
+		withLibrary(func(db *internallibrary.DB, w *tabwriter.Writer) error {
+			dupes, err := db.Duplicates()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, "TITLE\tYEAR\tSIZE\tPATH")
+			for _, records := range dupes {
+				for _, r := range records {
+					m := r.Item.MovieMetadata
+					fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", m.Title, m.ReleaseYear, r.Item.Size, r.Item.DestinationPath)
+				}
+			}
+			return nil
+		})
+	},
+}
+
+// withLibrary opens the library database read only and calls f with it and
+// a writer for tabular output to stdout.
+func withLibrary(f func(*internallibrary.DB, *tabwriter.Writer) error) {
+	cfg, err := config.LoadLibrary(rootCtx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	db, err := internallibrary.Open(cfg.DB, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if err := f(db, w); err != nil {
+		log.Fatal(err)
+	}
+	w.Flush()
+}
+
+func init() {
+	root.AddCommand(library)
+	library.AddCommand(libraryShows, libraryMissing, libraryDuplicates)
+	library.PersistentFlags().String("db", internallibrary.DefaultPath, "path of the library database")
+	if err := viper.BindPFlags(library.PersistentFlags()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package cmd
+
+import (
+	"github.com/rbtr/pachinko/internal/config"
+	"github.com/rbtr/pachinko/internal/pipeline"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// serve represents the serve command.
+var serve = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the sorting pipeline whenever new items arrive.",
+	Long: `
+Use this command to run pachinko as a long-running server.
+
+The pipeline is run once at startup, like "pachinko sort", and then again each
+time an input that receives items over time, like the webhook input, has new
+items. At least one such input must be configured.
+  $ pachinko serve
+
+The plugins are configured fresh for each run, so each run behaves exactly
+like "pachinko sort".
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.SetLevel(log.TraceLevel)
+		sortConf, err := config.LoadSort(rootCtx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sortConf.Validate(); err != nil {
+			log.Fatal(err)
+		}
+
+		for {
+			p := pipeline.NewPipeline()
+			if err := sortConf.ConfigurePipeline(p); err != nil {
+				log.Fatal(err)
+			}
+			if err := p.Run(rootCtx); err != nil {
+				log.Error(err)
+			}
+			if err := p.Wait(rootCtx); err != nil {
+				if rootCtx.Err() != nil {
+					return
+				}
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+func init() {
+	root.AddCommand(serve)
+}
@@ -10,10 +10,13 @@ package cmd
 import (
 	"github.com/rbtr/pachinko/internal/config"
 	"github.com/rbtr/pachinko/internal/pipeline"
+	"github.com/rbtr/pachinko/internal/plan"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+var planFile string
+
 // sort represents the sort command.
 var sort = &cobra.Command{
 	Use:   "sort",
@@ -26,6 +29,11 @@ With no arguments, sort will load the config from $HOME/.pachinko.yaml.
 
 If no config is provided, no plugins will be loaded and the pipeline will
 not do anything useful.
+
+With --plan, the inputs and processors run but the outputs don't. Instead,
+the actions that they would take are written to the plan file, which can be
+reviewed, edited, and then run with "pachinko apply".
+  $ pachinko sort --plan plan.json
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		log.SetLevel(log.TraceLevel)
@@ -38,6 +46,21 @@ not do anything useful.
 		}
 
 		p := pipeline.NewPipeline()
+		if planFile != "" {
+			pl := plan.New()
+			if err := sortConf.ConfigurePlan(p, pl); err != nil {
+				log.Fatal(err)
+			}
+			if err := p.Run(rootCtx); err != nil {
+				log.Fatal(err)
+			}
+			if err := pl.Write(planFile); err != nil {
+				log.Fatal(err)
+			}
+			log.Infof("wrote %d actions to %s", len(pl.Actions), planFile)
+			return
+		}
+
 		if err := sortConf.ConfigurePipeline(p); err != nil {
 			log.Fatal(err)
 		}
@@ -49,5 +72,6 @@ not do anything useful.
 }
 
 func init() {
+	sort.Flags().StringVar(&planFile, "plan", "", "write the actions to this plan file instead of taking them")
 	root.AddCommand(sort)
 }
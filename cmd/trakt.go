@@ -9,13 +9,18 @@ package cmd
 
 import (
 	"github.com/rbtr/pachinko/internal/config"
+	"github.com/rbtr/pachinko/internal/pipeline"
+	internalout "github.com/rbtr/pachinko/internal/plugin/output"
 	internaltrakt "github.com/rbtr/pachinko/internal/trakt"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var traktFile string
+var (
+	traktFile   string
+	traktRemove bool
+)
 
 // trakt represents the trakt connect command.
 var trakt = &cobra.Command{
@@ -74,11 +79,110 @@ it expires, you will need to rerun this to generate a new authorization.
 	},
 }
 
+// traktSync represents the trakt sync command.
+var traktSync = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the Trakt collection with the sorted media",
+	Long: `
+Use this command to make the Trakt collection match the media that has
+already been sorted.
+
+The destination directories of the tv-path-solver and movie-path-solver
+post-processors in the config are walked, and the media in them is identified
+by the pre- and intra-processors in the config. Media that is missing from
+the Trakt collection is added to it. With --remove, media in the collection
+that is missing from the destinations is removed from it, except for the
+episodes of shows with media in the destinations that can't be identified.
+
+Run with --dry-run to see the changes without making them.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sortConf, err := config.LoadSort(rootCtx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sortConf.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		traktConf, err := config.LoadTrakt(rootCtx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := traktConf.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		client, err := internaltrakt.Login(rootCtx, traktConf.Authfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		sorted := &internalout.Collector{}
+		p := pipeline.NewPipeline()
+		if err := sortConf.ConfigureSync(p, sorted); err != nil {
+			log.Fatal(err)
+		}
+		if err := p.Run(rootCtx); err != nil {
+			log.Fatal(err)
+		}
+
+		rec, err := client.Reconcile(rootCtx, sorted.Items)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, m := range rec.Unidentified {
+			log.Warnf("trakt sync: %s has no trakt, tvdb, tmdb, or imdb id, not adding", m.SourcePath)
+		}
+		if !traktRemove {
+			if n := rec.Remove.Len(); n > 0 {
+				log.Infof("trakt sync: %d items in the collection are missing from the destinations, run with --remove to remove them", n)
+			}
+			rec.Remove = &internaltrakt.CollectionBody{}
+		}
+		if rec.Remove.Len() > 0 && len(sorted.Items) == 0 {
+			log.Fatal("trakt sync: no media found in the destinations, refusing to empty the collection")
+		}
+		log.Infof("trakt sync: %d items to add, %d items to remove", rec.Add.Len(), rec.Remove.Len())
+		for _, m := range rec.Remove.Movies {
+			log.Infof("trakt sync: removing %s (%d)", m.Title, m.Year)
+		}
+		for _, s := range rec.Remove.Shows {
+			for _, season := range s.Seasons {
+				for _, e := range season.Episodes {
+					log.Infof("trakt sync: removing %s S%02dE%02d", s.Title, season.Number, e.Number)
+				}
+			}
+		}
+		if sortConf.DryRun {
+			return
+		}
+		for _, batch := range rec.Add.Split(internaltrakt.DefaultBatchSize) {
+			res, err := client.AddToCollection(rootCtx, batch)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Infof("trakt sync: added %d movies and %d episodes", res.Added.Movies, res.Added.Episodes)
+		}
+		for _, batch := range rec.Remove.Split(internaltrakt.DefaultBatchSize) {
+			res, err := client.RemoveFromCollection(rootCtx, batch)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Infof("trakt sync: removed %d movies and %d episodes", res.Deleted.Movies, res.Deleted.Episodes)
+		}
+	},
+}
+
 func init() {
 	root.AddCommand(trakt)
-	trakt.Flags().StringVar(&traktFile, "authfile", internaltrakt.DefaultAuthfile, "where to save the trakt authorization credential")
+	trakt.PersistentFlags().StringVar(&traktFile, "authfile", internaltrakt.DefaultAuthfile, "where to save the trakt authorization credential")
 	trakt.Flags().BoolP("overwrite", "f", false, "overwrite the authfile if it exists already")
 	if err := viper.BindPFlags(trakt.Flags()); err != nil {
 		log.Fatal(err)
 	}
+	if err := viper.BindPFlags(trakt.PersistentFlags()); err != nil {
+		log.Fatal(err)
+	}
+
+	trakt.AddCommand(traktSync)
+	traktSync.Flags().BoolVar(&traktRemove, "remove", false, "remove the media in the collection that is missing from the destinations")
 }
@@ -0,0 +1,280 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package archive extracts rar, zip, 7z, tar and gzip archives using pure-Go
+readers.
+*/
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode"
+	"github.com/pkg/errors"
+)
+
+// Format is an archive format.
+type Format string
+
+const (
+	Unknown  Format = ""
+	Gzip     Format = "gz"
+	Rar      Format = "rar"
+	SevenZip Format = "7z"
+	Tar      Format = "tar"
+	TarGzip  Format = "tgz"
+	Zip      Format = "zip"
+)
+
+var (
+	// partVolume matches the volumes of a new style multi-volume rar set
+	// like name.part01.rar, capturing the volume number.
+	partVolume = regexp.MustCompile(`(?i)\.part(\d+)\.rar$`)
+	// oldVolume matches the continuation volumes of an old style
+	// multi-volume rar set like name.r00.
+	oldVolume = regexp.MustCompile(`(?i)\.r\d{2,3}$`)
+)
+
+// Detect returns the Format of the archive at path by its extension.
+func Detect(path string) Format {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return TarGzip
+	case strings.HasSuffix(lower, ".gz"), strings.HasSuffix(lower, ".gzip"):
+		return Gzip
+	case strings.HasSuffix(lower, ".rar"), oldVolume.MatchString(lower):
+		return Rar
+	case strings.HasSuffix(lower, ".7z"):
+		return SevenZip
+	case strings.HasSuffix(lower, ".tar"):
+		return Tar
+	case strings.HasSuffix(lower, ".zip"):
+		return Zip
+	}
+	return Unknown
+}
+
+// IsContinuation is true if path is a second or later volume of a
+// multi-volume rar set, which is extracted through the first volume.
+func IsContinuation(path string) bool {
+	if oldVolume.MatchString(path) {
+		return true
+	}
+	if matches := partVolume.FindStringSubmatch(path); matches != nil {
+		return strings.TrimLeft(matches[1], "0") != "1"
+	}
+	return false
+}
+
+// Name returns the file name of the archive without its archive extensions,
+// suitable for naming the directory it is extracted in to.
+func Name(path string) string {
+	base := filepath.Base(path)
+	if loc := partVolume.FindStringIndex(base); loc != nil {
+		return base[:loc[0]]
+	}
+	lower := strings.ToLower(base)
+	for _, ext := range []string{".tar.gz", ".tgz", ".gzip", ".gz", ".rar", ".7z", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return base[:len(base)-len(ext)]
+		}
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Result lists what was read and written by an extraction.
+type Result struct {
+	// Files are the paths of the extracted files
+	Files []string
+	// Volumes are the paths of all of the archive files that were read
+	Volumes []string
+}
+
+// Extract extracts the archive at path in to the directory dest.
+func Extract(path, dest string) (*Result, error) {
+	res := &Result{Volumes: []string{path}}
+	var err error
+	switch Detect(path) {
+	case Rar:
+		err = extractRar(path, dest, res)
+	case SevenZip:
+		err = extract7z(path, dest, res)
+	case Tar:
+		err = withFile(path, func(f *os.File) error {
+			return extractTar(f, dest, res)
+		})
+	case TarGzip:
+		err = withFile(path, func(f *os.File) error {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			return extractTar(gz, dest, res)
+		})
+	case Gzip:
+		err = withFile(path, func(f *os.File) error {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			name := gz.Name
+			if name == "" {
+				name = Name(path)
+			}
+			return write(dest, name, gz, res)
+		})
+	case Zip:
+		err = extractZip(path, dest, res)
+	default:
+		err = errors.Errorf("unknown archive format for %s", path)
+	}
+	return res, err
+}
+
+func withFile(path string, f func(*os.File) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f(file)
+}
+
+// target resolves the archived name under dest, refusing names that would
+// escape it.
+func target(dest, name string) (string, error) {
+	path := filepath.Join(dest, filepath.FromSlash(name))
+	if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", errors.Errorf("archived file %s is outside of the destination", name)
+	}
+	return path, nil
+}
+
+// write copies the archived file to its target under dest.
+func write(dest, name string, r io.Reader, res *Result) error {
+	path, err := target(dest, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	// nolint: gosec
+	if _, err := io.Copy(out, r); err != nil {
+		return errors.Wrapf(err, "error extracting %s", name)
+	}
+	res.Files = append(res.Files, path)
+	return nil
+}
+
+func extractRar(path, dest string, res *Result) error {
+	r, err := rardecode.OpenReader(path, "")
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if h.IsDir {
+			continue
+		}
+		if err := write(dest, h.Name, r, res); err != nil {
+			return err
+		}
+	}
+	res.Volumes = r.Volumes()
+	return nil
+}
+
+func extract7z(path, dest string, res *Result) error {
+	r, err := sevenzip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = write(dest, f.Name, rc, res)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTar(in io.Reader, dest string, res *Result) error {
+	r := tar.NewReader(in)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := write(dest, h.Name, r, res); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(path, dest string, res *Result) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = write(dest, f.Name, rc, res)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
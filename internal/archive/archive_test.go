@@ -0,0 +1,164 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := map[string]Format{
+		"a.rar":         Rar,
+		"a.part01.rar":  Rar,
+		"a.r00":         Rar,
+		"a.7z":          SevenZip,
+		"a.tar":         Tar,
+		"a.tar.gz":      TarGzip,
+		"a.TGZ":         TarGzip,
+		"a.gz":          Gzip,
+		"a.zip":         Zip,
+		"a.mkv":         Unknown,
+		"a.rar.torrent": Unknown,
+	}
+	for in, want := range tests {
+		if got := Detect(in); got != want {
+			t.Errorf("%s: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIsContinuation(t *testing.T) {
+	tests := map[string]bool{
+		"a.rar":         false,
+		"a.r00":         true,
+		"a.r15":         true,
+		"a.part1.rar":   false,
+		"a.part01.rar":  false,
+		"a.part001.rar": false,
+		"a.part02.rar":  true,
+		"a.part10.rar":  true,
+	}
+	for in, want := range tests {
+		if got := IsContinuation(in); got != want {
+			t.Errorf("%s: got %t, want %t", in, got, want)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := map[string]string{
+		"/src/Show.S01E01.rar":        "Show.S01E01",
+		"/src/Show.S01E01.part01.rar": "Show.S01E01",
+		"/src/Movie (2020).tar.gz":    "Movie (2020)",
+		"/src/Movie (2020).zip":       "Movie (2020)",
+	}
+	for in, want := range tests {
+		if got := Name(in); got != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, body := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract_zip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "a.zip")
+	writeZip(t, src, map[string]string{"a.mkv": "a", "sub/b.srt": "b"})
+
+	dest := filepath.Join(dir, "out")
+	res, err := Extract(src, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(res.Files)
+	want := []string{filepath.Join(dest, "a.mkv"), filepath.Join(dest, "sub", "b.srt")}
+	if len(res.Files) != 2 || res.Files[0] != want[0] || res.Files[1] != want[1] {
+		t.Errorf("got %v, want %v", res.Files, want)
+	}
+	if b, err := ioutil.ReadFile(want[1]); err != nil || string(b) != "b" {
+		t.Errorf("got %s, %v", b, err)
+	}
+}
+
+func TestExtract_zipSlip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "a.zip")
+	writeZip(t, src, map[string]string{"../evil": "a"})
+	if _, err := Extract(src, filepath.Join(dir, "out")); err == nil {
+		t.Error("expected error extracting outside of dest")
+	}
+}
+
+func TestExtract_tarGzip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "a.tar.gz")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "dir/a.mkv", Typeflag: tar.TypeReg, Mode: 0644, Size: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	res, err := Extract(src, filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "out", "dir", "a.mkv"); len(res.Files) != 1 || res.Files[0] != want {
+		t.Errorf("got %v, want [%s]", res.Files, want)
+	}
+}
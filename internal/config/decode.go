@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// decodeHook converts durations and comma separated lists from their string
+// forms, and restores the on keys that YAML reads as true.
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	yamlKeys,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+)
+
+// yamlKeys renames the true key of maps to on. YAML 1.1 reads an unquoted on
+// as a boolean, which would make the on option of outputs have to be quoted.
+func yamlKeys(_ reflect.Type, _ reflect.Type, data interface{}) (interface{}, error) {
+	m, ok := data.(map[interface{}]interface{})
+	if !ok {
+		return data, nil
+	}
+	v, ok := m[true]
+	if !ok {
+		return data, nil
+	}
+	renamed := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		renamed[k] = v
+	}
+	delete(renamed, true)
+	renamed["on"] = v
+	return renamed, nil
+}
+
+// decode decodes a plugin config map in to the plugin, converting durations
+// and comma separated lists from their string forms.
+func decode(in, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: decodeHook,
+		Result:     out,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(in)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/mitchellh/mapstructure"
+	internalpre "github.com/rbtr/pachinko/internal/plugin/processor/pre"
 	"github.com/rbtr/pachinko/plugin/input"
 	"github.com/rbtr/pachinko/plugin/output"
 	"github.com/rbtr/pachinko/plugin/processor"
@@ -28,6 +29,10 @@ type Genconf struct {
 }
 
 func (c *Genconf) DefaultConfig(p *Sort) error {
+	if err := mapstructure.Decode(internalpre.NewCategorizer(), &p.Categorizer); err != nil {
+		return err
+	}
+
 	if len(c.Inputs) == 0 && len(c.Outputs) == 0 && len(c.Processors) == 0 {
 		// no plugins specified, dump configs for them all
 		for k := range input.Registry {
@@ -0,0 +1,37 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+type Library struct {
+	Root `mapstructure:",squash"`
+	DB   string `mapstructure:"db"`
+}
+
+func LoadLibrary(ctx context.Context) (*Library, error) {
+	cfg := &Library{}
+	cfg.ctx = ctx
+	viper.SetEnvKeyReplacer(strings.NewReplacer("_", "-"))
+	viper.AutomaticEnv()
+	err := viper.Unmarshal(cfg)
+	return cfg, err
+}
+
+func (l *Library) Validate() error {
+	if l.DB == "" {
+		return errors.New("db must be set")
+	}
+	return nil
+}
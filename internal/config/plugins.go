@@ -0,0 +1,281 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/filesystem"
+	"github.com/rbtr/pachinko/internal/pipeline"
+	internalpre "github.com/rbtr/pachinko/internal/plugin/processor/pre"
+	"github.com/rbtr/pachinko/plugin/input"
+	"github.com/rbtr/pachinko/plugin/output"
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/plugin/processor/post"
+)
+
+// validator is implemented by the plugins that check their options before
+// they are initialized, see input.Validator, processor.Validator, and
+// output.Validator.
+type validator interface {
+	Validate() error
+}
+
+// newPlugin looks up the plugin by the name in its config and decodes the
+// config in to it strictly, without the common keys of its kind, returning it
+// and its label for errors.
+func newPlugin(label, kind string, common []string, p map[string]interface{}, names []string, lookup func(string) (interface{}, bool)) (interface{}, string, error) {
+	name, _ := p["name"].(string)
+	if name == "" {
+		return nil, label, errors.Errorf("%s: name must be set", label)
+	}
+	plugin, ok := lookup(name)
+	if !ok {
+		msg := fmt.Sprintf("%s: unknown %s %s", label, kind, name)
+		if s := suggest(name, names); s != "" {
+			msg += fmt.Sprintf(", did you mean %s?", s)
+		}
+		return nil, label, errors.New(msg)
+	}
+	label = fmt.Sprintf("%s (%s)", label, name)
+	if err := decodeStrict(label, common, p, plugin); err != nil {
+		return nil, label, err
+	}
+	if v, ok := plugin.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, label, errors.Wrap(err, label)
+		}
+	}
+	return plugin, label, nil
+}
+
+func (c *Sort) newInput(i int, p map[string]interface{}) (input.Input, error) {
+	names := []string{}
+	for name := range input.Registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	plugin, _, err := newPlugin(fmt.Sprintf("inputs[%d]", i), "input", inputKeys, p, names, func(name string) (interface{}, bool) {
+		initializer, ok := input.Registry[name]
+		if !ok {
+			return nil, false
+		}
+		return initializer(), true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plugin.(input.Input), nil
+}
+
+// outputConfig is a configured output, with an instance for each of its
+// concurrency.
+type outputConfig struct {
+	name      string
+	filter    pipeline.Filter
+	dep       pipeline.Dependency
+	opts      pipeline.Options
+	instances []output.Output
+}
+
+func (c *Sort) newOutput(i int, p map[string]interface{}) (*outputConfig, error) {
+	names := []string{}
+	for name := range output.Registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	o := &outputConfig{}
+	if err := decode(p, &o.opts); err != nil {
+		return nil, err
+	}
+	if o.opts.Concurrency <= 0 {
+		o.opts.Concurrency = 1
+	}
+	var label string
+	for n := 0; n < o.opts.Concurrency; n++ {
+		plugin, l, err := newPlugin(fmt.Sprintf("outputs[%d]", i), "output", outputKeys, p, names, func(name string) (interface{}, bool) {
+			initializer, ok := output.Registry[name]
+			if !ok {
+				return nil, false
+			}
+			return initializer(), true
+		})
+		if err != nil {
+			return nil, err
+		}
+		label = l
+		o.instances = append(o.instances, plugin.(output.Output))
+	}
+	o.name, _ = p["name"].(string)
+
+	if err := decode(p, &o.filter); err != nil {
+		return nil, errors.Wrap(err, label)
+	}
+	if err := o.filter.Compile(); err != nil {
+		return nil, errors.Wrap(err, label)
+	}
+	if err := decode(p, &o.dep); err != nil {
+		return nil, errors.Wrap(err, label)
+	}
+	outcomes := []string{output.Moved, output.Deleted, output.Failed, output.Skipped}
+	for _, on := range o.dep.On {
+		if !contains(outcomes, on) {
+			msg := fmt.Sprintf("%s: unknown outcome %s", label, on)
+			if s := suggest(on, outcomes); s != "" {
+				msg += fmt.Sprintf(", did you mean %s?", s)
+			}
+			return nil, errors.New(msg)
+		}
+	}
+	return o, nil
+}
+
+func (c *Sort) newProcessor(t processor.Type, i int, p map[string]interface{}) (processor.Processor, pipeline.Filter, error) {
+	var filter pipeline.Filter
+	names := []string{}
+	for name := range processor.Registry[t] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mustExist := t == processor.Post && c.destDirsMustExist()
+	plugin, label, err := newPlugin(fmt.Sprintf("processors.%s[%d]", t, i), string(t)+"-processor", processorKeys, p, names, func(name string) (interface{}, bool) {
+		initializer, ok := processor.Registry[t][name]
+		if !ok {
+			return nil, false
+		}
+		plugin := initializer()
+		switch solver := plugin.(type) {
+		case *post.TVPathSolver:
+			solver.DestDirMustExist = mustExist
+		case *post.MoviePathSolver:
+			solver.DestDirMustExist = mustExist
+		}
+		return plugin, true
+	})
+	if err != nil {
+		return nil, filter, err
+	}
+	if err := decode(p, &filter); err != nil {
+		return nil, filter, errors.Wrap(err, label)
+	}
+	if err := filter.Compile(); err != nil {
+		return nil, filter, errors.Wrap(err, label)
+	}
+	return plugin.(processor.Processor), filter, nil
+}
+
+// destDirsMustExist is whether the dest-dirs of the path solvers must exist,
+// because they are moved in to by path-movers on the local filesystem that
+// don't create the directories.
+func (c *Sort) destDirsMustExist() bool {
+	mustExist := false
+	for _, p := range c.Outputs {
+		if name, _ := p["name"].(string); name != "path-mover" {
+			continue
+		}
+		mover, ok := output.Registry["path-mover"]().(*output.FilepathMover)
+		if !ok || decode(p, mover) != nil {
+			continue
+		}
+		dest := mover.DestFilesystem.Type
+		if dest == "" {
+			dest = mover.Filesystem.Type
+		}
+		if dest != "" && dest != filesystem.Local {
+			continue
+		}
+		if mover.CreateDirs {
+			return false
+		}
+		mustExist = true
+	}
+	return mustExist
+}
+
+// checkType checks that the processor type is one of the types.
+func checkType(t processor.Type) error {
+	names := []string{}
+	for _, known := range processor.Types {
+		if t == known {
+			return nil
+		}
+		names = append(names, string(known))
+	}
+	msg := fmt.Sprintf("processors: unknown type %s", t)
+	if s := suggest(string(t), names); s != "" {
+		msg += fmt.Sprintf(", did you mean %s?", s)
+	}
+	return errors.New(msg)
+}
+
+// Check checks the whole config without initializing any of the plugins,
+// returning all of the errors: plugins that are missing or unknown, options
+// that the plugins don't have, and options that the plugins' Validate
+// methods reject.
+func (c *Sort) Check() []error {
+	errs := []error{}
+	if err := c.checkRoot(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := decodeStrict("categorizer", nil, c.Categorizer, internalpre.NewCategorizer()); err != nil {
+		errs = append(errs, err)
+	}
+	for i, p := range c.Inputs {
+		if _, err := c.newInput(i, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	outputs := []*outputConfig{}
+	for i, p := range c.Outputs {
+		o, err := c.newOutput(i, p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		outputs = append(outputs, o)
+	}
+	reporters := map[string]bool{}
+	names := []string{}
+	for _, o := range outputs {
+		_, ok := o.instances[0].(output.Reporter)
+		reporters[o.name] = reporters[o.name] || ok
+		names = append(names, o.name)
+	}
+	for _, o := range outputs {
+		for _, after := range o.dep.After {
+			reporter, ok := reporters[after]
+			switch {
+			case !ok:
+				msg := fmt.Sprintf("outputs (%s): runs after %s, which is not configured", o.name, after)
+				if s := suggest(after, names); s != "" {
+					msg += fmt.Sprintf(", did you mean %s?", s)
+				}
+				errs = append(errs, errors.New(msg))
+			case !reporter:
+				errs = append(errs, errors.Errorf("outputs (%s): runs after %s, which does not report outcomes", o.name, after))
+			}
+		}
+	}
+
+	for t := range c.Processors {
+		if err := checkType(t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, t := range processor.Types {
+		for i, p := range c.Processors[t] {
+			if _, _, err := c.newProcessor(t, i, p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
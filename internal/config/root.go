@@ -16,9 +16,9 @@ import (
 type Root struct {
 	// nolint: structcheck
 	ctx       context.Context
-	DryRun    bool   `mapstructure:"dry-run"`
-	LogLevel  string `mapstructure:"log-level"`
-	LogFormat string `mapstructure:"log-format"`
+	DryRun    bool   `mapstructure:"dry-run" description:"log the changes that would be made instead of making them"`
+	LogLevel  string `mapstructure:"log-level" description:"the level to log at" enum:",trace,debug,info,warn,error,fatal,panic"`
+	LogFormat string `mapstructure:"log-format" description:"the format of the logs" enum:",text,json"`
 }
 
 func (c *Root) configLogger() {
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"sort"
+
+	"github.com/rbtr/pachinko/internal/pipeline"
+	internalpre "github.com/rbtr/pachinko/internal/plugin/processor/pre"
+	"github.com/rbtr/pachinko/internal/schema"
+	"github.com/rbtr/pachinko/plugin/input"
+	"github.com/rbtr/pachinko/plugin/output"
+	"github.com/rbtr/pachinko/plugin/processor"
+)
+
+// pluginSchema is the schema of a plugin in a list of plugins, with its
+// name and the options that the pipeline handles for it.
+func pluginSchema(kind, name string, plugin interface{}, common ...interface{}) *schema.Schema {
+	s := schema.Of(plugin)
+	s.Title = name
+	for _, c := range common {
+		s.Merge(schema.Of(c))
+	}
+	s.Properties["name"] = &schema.Schema{
+		Type:        "string",
+		Const:       name,
+		Description: "the " + kind + " plugin",
+	}
+	s.Required = []string{"name"}
+	return s
+}
+
+// plugins is the schema of a list of plugins, each of which is one of the
+// registered plugins.
+func plugins(description string, names []string, item func(string) *schema.Schema) *schema.Schema {
+	sort.Strings(names)
+	s := &schema.Schema{
+		Type:        "array",
+		Description: description,
+		Items:       &schema.Schema{},
+	}
+	for _, name := range names {
+		s.Items.OneOf = append(s.Items.OneOf, item(name))
+	}
+	return s
+}
+
+// Schema is the JSON Schema of the sort config, with the options of every
+// registered plugin, for editors to validate and complete configs with.
+func Schema() *schema.Schema {
+	s := schema.Of(&Root{})
+	s.Schema = schema.Draft
+	s.Title = "pachinko"
+	s.Description = "the config of a pachinko sort"
+	// the config file is shared with the other commands, like library
+	s.AdditionalProperties = nil
+
+	s.Properties["pipeline"] = schema.Of(&pipeline.Config{})
+	s.Properties["pipeline"].Description = "tunables of the pipeline"
+	s.Properties["categorizer"] = schema.Of(internalpre.NewCategorizer())
+	s.Properties["categorizer"].Description = "how the items are categorized, before the pre-processors"
+
+	names := []string{}
+	for name := range input.Registry {
+		names = append(names, name)
+	}
+	s.Properties["inputs"] = plugins("the inputs that items are read from", names, func(name string) *schema.Schema {
+		return pluginSchema("input", name, input.Registry[name]())
+	})
+
+	names = []string{}
+	for name := range output.Registry {
+		names = append(names, name)
+	}
+	s.Properties["outputs"] = plugins("the outputs that items are sent to", names, func(name string) *schema.Schema {
+		return pluginSchema("output", name, output.Registry[name](),
+			&pipeline.Filter{}, &pipeline.Dependency{}, &pipeline.Options{})
+	})
+
+	processors := &schema.Schema{
+		Type:                 "object",
+		Description:          "the processors that identify and sort the items, by when they run",
+		Properties:           map[string]*schema.Schema{},
+		AdditionalProperties: false,
+	}
+	descriptions := map[processor.Type]string{
+		processor.Pre:   "pre-processors, which identify the items by their paths",
+		processor.Intra: "intra-processors, which add metadata to the identified items",
+		processor.Post:  "post-processors, which solve the destinations of the items",
+	}
+	for _, t := range processor.Types {
+		t := t
+		names := []string{}
+		for name := range processor.Registry[t] {
+			names = append(names, name)
+		}
+		processors.Properties[string(t)] = plugins(descriptions[t], names, func(name string) *schema.Schema {
+			return pluginSchema(string(t)+"-processor", name, processor.Registry[t][name](), &pipeline.Filter{})
+		})
+	}
+	s.Properties["processors"] = processors
+	return s
+}
@@ -0,0 +1,52 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rbtr/pachinko/internal/schema"
+	"github.com/rbtr/pachinko/plugin/input"
+	"github.com/rbtr/pachinko/plugin/output"
+)
+
+// TestSchemaDocumented checks that every option of every plugin has a
+// description, so that genconf and editors can show it.
+func TestSchemaDocumented(t *testing.T) {
+	var walk func(path string, s *schema.Schema)
+	walk = func(path string, s *schema.Schema) {
+		for k, p := range s.Properties {
+			if p.Description == "" {
+				t.Errorf("%s.%s has no description", path, k)
+			}
+			walk(path+"."+k, p)
+		}
+		if s.Items != nil {
+			walk(path+"[]", s.Items)
+		}
+		for _, o := range s.OneOf {
+			walk(path+"("+o.Title+")", o)
+		}
+		if additional, ok := s.AdditionalProperties.(*schema.Schema); ok {
+			walk(path+"{}", additional)
+		}
+	}
+	s := Schema()
+	walk("", s)
+
+	if got, want := len(s.Properties["inputs"].Items.OneOf), len(input.Registry); got != want {
+		t.Errorf("got %d inputs, want %d", got, want)
+	}
+	if got, want := len(s.Properties["outputs"].Items.OneOf), len(output.Registry); got != want {
+		t.Errorf("got %d outputs, want %d", got, want)
+	}
+	if _, err := json.Marshal(s); err != nil {
+		t.Error(err)
+	}
+}
@@ -9,90 +9,276 @@ package config
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"github.com/rbtr/pachinko/internal/pipeline"
+	"github.com/rbtr/pachinko/internal/plan"
+	internalin "github.com/rbtr/pachinko/internal/plugin/input"
 	internalout "github.com/rbtr/pachinko/internal/plugin/output"
 	internalpre "github.com/rbtr/pachinko/internal/plugin/processor/pre"
 	"github.com/rbtr/pachinko/plugin/input"
 	"github.com/rbtr/pachinko/plugin/output"
 	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/plugin/processor/post"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
 type Sort struct {
-	Root       `mapstructure:",squash"`
-	Pipeline   pipeline.Config                             `mapstructure:"pipeline"`
-	Inputs     []map[string]interface{}                    `mapstructure:"inputs"`
-	Outputs    []map[string]interface{}                    `mapstructure:"outputs"`
-	Processors map[processor.Type][]map[string]interface{} `mapstructure:"processors"`
+	Root        `mapstructure:",squash"`
+	Pipeline    pipeline.Config                             `mapstructure:"pipeline"`
+	Categorizer map[string]interface{}                      `mapstructure:"categorizer"`
+	Inputs      []map[string]interface{}                    `mapstructure:"inputs"`
+	Outputs     []map[string]interface{}                    `mapstructure:"outputs"`
+	Processors  map[processor.Type][]map[string]interface{} `mapstructure:"processors"`
+
+	// settings are the loaded settings, to check for unknown keys
+	settings map[string]interface{}
 }
 
+// ConfigurePipeline configures the pipeline with the inputs, processors,
+// and outputs.
 func (c *Sort) ConfigurePipeline(pipe *pipeline.Pipeline) error {
+	if err := c.checkRoot(); err != nil {
+		return err
+	}
+	if err := c.configureInputs(pipe); err != nil {
+		return err
+	}
+	if err := c.configureOutputs(pipe); err != nil {
+		return err
+	}
+	return c.configureProcessors(pipe, true, c.DryRun, processor.Types...)
+}
+
+// ConfigurePlan configures the pipeline with the inputs and processors, and
+// an output that adds the actions the outputs would take to the plan.
+func (c *Sort) ConfigurePlan(pipe *pipeline.Pipeline, pl *plan.Plan) error {
+	if err := c.checkRoot(); err != nil {
+		return err
+	}
+	if err := c.configureInputs(pipe); err != nil {
+		return err
+	}
+	pipe.WithOutputs(&internalout.Planner{Plan: pl})
+	// the processors run for real, so the plan has the extracted files
+	return c.configureProcessors(pipe, true, false, processor.Types...)
+}
+
+// DestinationRoots are the directories that the path solvers sort tv and
+// movies in to.
+func (c *Sort) DestinationRoots() ([]string, error) {
+	roots := []string{}
+	for _, p := range c.Processors[processor.Post] {
+		name, _ := p["name"].(string)
+		initializer, ok := processor.Registry[processor.Post][name]
+		if !ok {
+			continue
+		}
+		plugin := initializer()
+		if err := decode(p, plugin); err != nil {
+			return nil, err
+		}
+		switch solver := plugin.(type) {
+		case *post.TVPathSolver:
+			roots = append(roots, filepath.Join(solver.DestDir, solver.TVPrefix))
+		case *post.MoviePathSolver:
+			roots = append(roots, filepath.Join(solver.DestDir, solver.MoviesPrefix))
+		}
+	}
+	return roots, nil
+}
+
+// ConfigureSync configures the pipeline to identify the media already sorted
+// in to the destination roots, with the pre- and intra-processors, and send
+// it to the output. The processors aren't scoped to their sources, because
+// the items come from the destinations instead.
+func (c *Sort) ConfigureSync(pipe *pipeline.Pipeline, out output.Output) error {
+	if err := c.checkRoot(); err != nil {
+		return err
+	}
 	if err := mapstructure.Decode(c.Pipeline, pipe); err != nil {
 		return err
 	}
-	for _, p := range c.Inputs {
-		if name, ok := p["name"]; ok {
-			if initializer, ok := input.Registry[name.(string)]; ok {
-				plugin := initializer()
-				if err := mapstructure.Decode(p, plugin); err != nil {
-					return err
-				}
-				if err := plugin.Init(c.ctx); err != nil {
-					return err
-				}
-				pipe.WithInputs(plugin)
-			}
+	roots, err := c.DestinationRoots()
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return errors.New("no tv-path-solver or movie-path-solver post-processors are configured")
+	}
+	dirs := []map[string]interface{}{}
+	for _, root := range roots {
+		dirs = append(dirs, map[string]interface{}{"path": root})
+	}
+	in := input.Registry["filepath"]()
+	if err := decode(map[string]interface{}{"src-dirs": dirs}, in); err != nil {
+		return err
+	}
+	if err := in.Init(c.ctx); err != nil {
+		return err
+	}
+	pipe.WithInputs(in)
+	if err := out.Init(c.ctx, output.Config{DryRun: c.DryRun}); err != nil {
+		return err
+	}
+	pipe.WithOutputs(out)
+	return c.configureProcessors(pipe, false, c.DryRun, processor.Pre, processor.Intra)
+}
+
+// ConfigureApply configures the pipeline with the items of the plan as the
+// input and the outputs, skipping the processors.
+func (c *Sort) ConfigureApply(pipe *pipeline.Pipeline, pl *plan.Plan) error {
+	if err := c.checkRoot(); err != nil {
+		return err
+	}
+	if err := mapstructure.Decode(c.Pipeline, pipe); err != nil {
+		return err
+	}
+	pipe.WithInputs(&internalin.PlanInput{Plan: pl})
+	return c.configureOutputs(pipe)
+}
+
+func (c *Sort) configureInputs(pipe *pipeline.Pipeline) error {
+	if err := mapstructure.Decode(c.Pipeline, pipe); err != nil {
+		return err
+	}
+	for i, p := range c.Inputs {
+		plugin, err := c.newInput(i, p)
+		if err != nil {
+			return err
 		}
+		if err := plugin.Init(c.ctx); err != nil {
+			return err
+		}
+		pipe.WithInputs(plugin)
 	}
+	return nil
+}
 
+func (c *Sort) configureOutputs(pipe *pipeline.Pipeline) error {
 	ocfg := output.Config{
 		DryRun: c.DryRun,
 	}
 
-	for _, p := range c.Outputs {
-		if name, ok := p["name"]; ok {
-			if initializer, ok := output.Registry[name.(string)]; ok {
-				plugin := initializer()
-				if err := mapstructure.Decode(p, plugin); err != nil {
-					return err
-				}
-				if err := plugin.Init(c.ctx, ocfg); err != nil {
-					return err
-				}
-				pipe.WithOutputs(plugin)
+	outputs := []*outputConfig{}
+	for i, p := range c.Outputs {
+		o, err := c.newOutput(i, p)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, o)
+	}
+	// the outputs that move and delete the items, which the followers and
+	// the deleter run after
+	movers := follow(outputs)
+	for _, o := range outputs {
+		instances := []output.Output{}
+		for _, plugin := range o.instances {
+			if err := plugin.Init(c.ctx, ocfg); err != nil {
+				return err
 			}
+			instances = append(instances, pipeline.FilterOutput(plugin, o.filter))
 		}
+		pipe.WithOutput(o.name, o.dep, o.opts, instances...)
 	}
 
+	// the deleter only deletes once the items have been moved, and never
+	// the directories of the items that failed to
 	deleter := &internalout.Deleter{}
 	if err := deleter.Init(c.ctx, ocfg); err != nil {
 		return err
 	}
-	pipe.WithOutputs(deleter)
+	if len(movers) == 0 {
+		pipe.WithOutputs(deleter)
+		return nil
+	}
+	pipe.WithOutput("", pipeline.Dependency{
+		After: movers,
+		On:    []string{output.Skipped, output.Failed},
+	}, pipeline.Options{}, deleter)
+	return nil
+}
 
+// follow defaults the followers among the outputs to run after all of the
+// movers, on the outcomes they act on, and returns the movers. Followers
+// that are configured to run after other outputs, or on other outcomes,
+// keep their config.
+func follow(outputs []*outputConfig) []string {
+	movers := []string{}
+	for _, o := range outputs {
+		if _, ok := o.instances[0].(output.Mover); ok && !contains(movers, o.name) {
+			movers = append(movers, o.name)
+		}
+	}
+	for _, o := range outputs {
+		follower, ok := o.instances[0].(output.Follower)
+		if !ok || contains(movers, o.name) {
+			continue
+		}
+		if len(o.dep.After) == 0 {
+			if len(movers) == 0 {
+				log.Warnf("outputs (%s): no outputs move the items to run after, using the intended outcomes instead", o.name)
+				continue
+			}
+			o.dep.After = movers
+		}
+		if len(o.dep.On) == 0 {
+			o.dep.On = follower.Outcomes()
+		}
+	}
+	return movers
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// configureProcessors configures the processors of the types, scoped to their
+// sources if scoped is set, and only logging their changes if dryRun is set.
+func (c *Sort) configureProcessors(pipe *pipeline.Pipeline, scoped, dryRun bool, types ...processor.Type) error {
 	categorizer := internalpre.NewCategorizer()
+	if err := decodeStrict("categorizer", nil, c.Categorizer, categorizer); err != nil {
+		return err
+	}
 	if err := categorizer.Init(c.ctx); err != nil {
 		return err
 	}
 	pipe.WithProcessors(categorizer)
 
-	for _, t := range processor.Types {
-		for _, p := range c.Processors[t] {
-			if name, ok := p["name"]; ok {
-				if initializer, ok := processor.Registry[t][name.(string)]; ok {
-					plugin := initializer()
-					if err := mapstructure.Decode(p, plugin); err != nil {
-						return err
-					}
-					if err := plugin.Init(c.ctx); err != nil {
-						return err
-					}
-					pipe.WithProcessors(plugin)
-				}
+	for t := range c.Processors {
+		if err := checkType(t); err != nil {
+			return err
+		}
+	}
+	for _, t := range types {
+		for i, p := range c.Processors[t] {
+			plugin, filter, err := c.newProcessor(t, i, p)
+			if err != nil {
+				return err
+			}
+			if u, ok := plugin.(processor.CategorizerUser); ok {
+				u.UseCategorizer(categorizer)
+			}
+			if u, ok := plugin.(processor.DryRunUser); ok {
+				u.UseDryRun(dryRun)
+			}
+			if err := plugin.Init(c.ctx); err != nil {
+				return err
+			}
+			if !scoped {
+				pipe.WithProcessors(plugin)
+				continue
 			}
+			pipe.WithProcessors(pipeline.FilterProcessor(plugin, filter))
 		}
 	}
 
@@ -116,6 +302,7 @@ func LoadSort(ctx context.Context) (*Sort, error) {
 	cfg := NewSort(ctx)
 	viper.SetEnvKeyReplacer(strings.NewReplacer("_", "-"))
 	viper.AutomaticEnv()
-	err := viper.Unmarshal(cfg)
+	cfg.settings = viper.AllSettings()
+	err := viper.Unmarshal(cfg, viper.DecodeHook(decodeHook))
 	return cfg, err
 }
@@ -0,0 +1,43 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rbtr/pachinko/internal/pipeline"
+	"github.com/rbtr/pachinko/plugin/output"
+)
+
+func TestFollow(t *testing.T) {
+	plex := &outputConfig{name: "plex", instances: []output.Output{&output.PlexRefresh{}}}
+	notify := &outputConfig{
+		name:      "notify",
+		dep:       pipeline.Dependency{After: []string{"s3-mover"}, On: []string{output.Failed}},
+		instances: []output.Output{&output.Notifier{}},
+	}
+	outputs := []*outputConfig{
+		plex,
+		{name: "path-mover", instances: []output.Output{&output.FilepathMover{}}},
+		{name: "s3-mover", instances: []output.Output{&output.S3Mover{}}},
+		// reports outcomes, but doesn't move anything
+		{name: "exec", instances: []output.Output{&output.ExecOutput{}}},
+		notify,
+	}
+	movers := follow(outputs)
+	if want := []string{"path-mover", "s3-mover"}; !reflect.DeepEqual(movers, want) {
+		t.Errorf("got movers %v, want %v", movers, want)
+	}
+	if on := []string{output.Moved, output.Failed}; !reflect.DeepEqual(plex.dep.After, movers) || !reflect.DeepEqual(plex.dep.On, on) {
+		t.Errorf("got plex %+v, want it after %v on %v", plex.dep, movers, on)
+	}
+	if want := []string{"s3-mover"}; !reflect.DeepEqual(notify.dep.After, want) || len(notify.dep.On) != 1 {
+		t.Errorf("got notify %+v, want its config kept", notify.dep)
+	}
+}
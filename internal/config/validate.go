@@ -0,0 +1,237 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// The options of each kind of plugin that the pipeline handles, rather than
+// the plugin: inputs are only named, processors can also be filtered, and
+// outputs can also depend on other outputs and be tuned.
+var (
+	inputKeys     = []string{"name"}
+	processorKeys = []string{"name", "sources", "when"}
+	outputKeys    = []string{"name", "sources", "when", "after", "on", "buffer", "concurrency"}
+)
+
+// decodeStrict decodes a plugin config map in to the plugin like decode, but
+// without the common keys, and returns an error naming the keys that aren't
+// options of the plugin.
+func decodeStrict(label string, common []string, in map[string]interface{}, out interface{}) error {
+	stripped := map[string]interface{}{}
+	for k, v := range in {
+		stripped[k] = v
+	}
+	for _, k := range common {
+		delete(stripped, k)
+	}
+	md := &mapstructure.Metadata{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: decodeHook,
+		Metadata:   md,
+		Result:     out,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(stripped); err != nil {
+		return errors.Wrap(err, label)
+	}
+	return unknownKeys(label, md.Unused, out)
+}
+
+// sharedKeys are the top level keys of the other commands, which share the
+// config file and flags with sort.
+func sharedKeys() []string {
+	shared := []string{"config"}
+	for _, c := range []interface{}{&Trakt{}, &Library{}, &Genconf{}} {
+		shared = append(shared, keys(reflect.TypeOf(c))...)
+	}
+	return shared
+}
+
+// checkRoot checks that the top level keys of the config, and the keys of
+// the sections that aren't plugins, are options of sort or of the other
+// commands, so that a misspelled section isn't silently ignored.
+func (c *Sort) checkRoot() error {
+	root := map[string]interface{}{}
+	for k, v := range c.settings {
+		root[k] = v
+	}
+	own := keys(reflect.TypeOf(c))
+	for _, k := range sharedKeys() {
+		if !contains(own, k) {
+			delete(root, k)
+		}
+	}
+	md := &mapstructure.Metadata{}
+	out := &Sort{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       decodeHook,
+		Metadata:         md,
+		Result:           out,
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(root); err != nil {
+		return errors.Wrap(err, "config")
+	}
+	return unknownKeys("config", md.Unused, out)
+}
+
+// unknownKeys returns an error naming the keys that aren't options of out,
+// with the option they are closest to, or nil if there are none.
+func unknownKeys(label string, unused []string, out interface{}) error {
+	if len(unused) == 0 {
+		return nil
+	}
+	sort.Strings(unused)
+	msgs := []string{}
+	for _, key := range unused {
+		msg := fmt.Sprintf("unknown option %s", key)
+		if s := suggest(lastKey(key), keys(parentType(reflect.TypeOf(out), key))); s != "" {
+			msg += fmt.Sprintf(", did you mean %s%s?", key[:strings.LastIndex(key, ".")+1], s)
+		}
+		msgs = append(msgs, msg)
+	}
+	return errors.Errorf("%s: %s", label, strings.Join(msgs, "; "))
+}
+
+var index = regexp.MustCompile(`\[[^\]]*\]`)
+
+// lastKey is the last key of a nested key like src-dirs[0].path.
+func lastKey(key string) string {
+	parts := strings.Split(index.ReplaceAllString(key, ""), ".")
+	return parts[len(parts)-1]
+}
+
+// elem dereferences pointers, slices, and maps to the type of their
+// elements.
+func elem(t reflect.Type) reflect.Type {
+	for t != nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+	return t
+}
+
+// fields calls the func with the mapstructure key and type of each field of
+// the struct type, including squashed structs.
+func fields(t reflect.Type, f func(key string, t reflect.Type)) {
+	t = elem(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("mapstructure"), ",")
+		squash := false
+		for _, opt := range tag[1:] {
+			squash = squash || opt == "squash"
+		}
+		switch {
+		case squash:
+			fields(field.Type, f)
+		case field.PkgPath != "" || tag[0] == "-":
+			// unexported
+		case tag[0] != "":
+			f(tag[0], field.Type)
+		default:
+			f(field.Name, field.Type)
+		}
+	}
+}
+
+// keys are the mapstructure keys of the struct type.
+func keys(t reflect.Type) []string {
+	ks := []string{}
+	fields(t, func(key string, _ reflect.Type) {
+		ks = append(ks, key)
+	})
+	return ks
+}
+
+// parentType is the type of the struct that a nested key like
+// src-dirs[0].path is in.
+func parentType(t reflect.Type, key string) reflect.Type {
+	parts := strings.Split(index.ReplaceAllString(key, ""), ".")
+	for _, part := range parts[:len(parts)-1] {
+		var next reflect.Type
+		fields(t, func(key string, ft reflect.Type) {
+			if strings.EqualFold(key, part) {
+				next = ft
+			}
+		})
+		if next == nil {
+			return nil
+		}
+		t = next
+	}
+	return t
+}
+
+// suggest returns the candidate closest to the string, if it is close
+// enough to be a typo of it.
+func suggest(s string, candidates []string) string {
+	best, distance := "", -1
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(s), strings.ToLower(c)); distance < 0 || d < distance {
+			best, distance = c, d
+		}
+	}
+	if distance < 0 || (distance > 2 && distance > len(s)/3) {
+		return ""
+	}
+	return best
+}
+
+// levenshtein is the edit distance between the strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func min(ns ...int) int {
+	m := ns[0]
+	for _, n := range ns[1:] {
+		if n < m {
+			m = n
+		}
+	}
+	return m
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rbtr/pachinko/internal/pipeline"
+	_ "github.com/rbtr/pachinko/plugin"
+	"github.com/rbtr/pachinko/plugin/processor"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	type nested struct {
+		Type string `mapstructure:"type"`
+	}
+	type plugin struct {
+		CreateDirs bool   `mapstructure:"create-dirs"`
+		Nested     nested `mapstructure:"nested"`
+	}
+	tests := []struct {
+		name string
+		in   map[string]interface{}
+		want string
+	}{
+		{
+			name: "valid",
+			in:   map[string]interface{}{"name": "p", "after": []string{"q"}, "create-dirs": true},
+		},
+		{
+			name: "typo",
+			in:   map[string]interface{}{"create_dirs": true},
+			want: "p: unknown option create_dirs, did you mean create-dirs?",
+		},
+		{
+			name: "nested typo",
+			in:   map[string]interface{}{"nested": map[string]interface{}{"typ": "s3"}},
+			want: "p: unknown option nested.typ, did you mean nested.type?",
+		},
+		{
+			name: "unknown",
+			in:   map[string]interface{}{"bogus": 1},
+			want: "p: unknown option bogus",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeStrict("p", outputKeys, tt.in, &plugin{})
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := NewSort(context.TODO())
+	c.Inputs = []map[string]interface{}{
+		{"name": "filepath", "src-dir": "/does/not/exist"},
+		{"name": "list", "sources": []string{"tv"}},
+	}
+	c.Outputs = []map[string]interface{}{
+		{"name": "path_mover"},
+		{"name": "stdout", "after": []string{"stdout"}},
+		{"name": "exec", "command": []string{"true"}, "on": []string{"faild"}},
+		{"name": "exec", "command": []string{"true"}, "after": []string{"path-mover"}},
+	}
+	c.Processors = map[processor.Type][]map[string]interface{}{
+		processor.Pre:  {{"name": "tv", "after": []string{"path-mover"}}},
+		processor.Post: {{"name": "tv-path-solver", "dest-dir": ""}},
+		"psot":         {},
+	}
+	want := []string{
+		"inputs[0] (filepath)",
+		"outputs[0]: unknown output path_mover, did you mean path-mover?",
+		"runs after stdout, which does not report outcomes",
+		"unknown outcome faild, did you mean failed?",
+		"runs after path-mover, which is not configured",
+		"inputs[1] (list): unknown option sources",
+		"processors.pre[0] (tv): unknown option after",
+		"processors: unknown type psot, did you mean post?",
+		"processors.post[0] (tv-path-solver)",
+	}
+	errs := c.Check()
+	for _, w := range want {
+		found := false
+		for _, err := range errs {
+			found = found || strings.Contains(err.Error(), w)
+		}
+		if !found {
+			t.Errorf("missing error %q in %v", w, errs)
+		}
+	}
+}
+
+func TestCheck_root(t *testing.T) {
+	c := NewSort(context.TODO())
+	c.settings = map[string]interface{}{
+		"ouptuts":  []interface{}{map[string]interface{}{"name": "path-mover"}},
+		"pipeline": map[string]interface{}{"bufer": 10},
+		"dry-run":  "true",
+		// shared with the trakt command
+		"authfile": "/etc/pachinko/trakt.json",
+	}
+	want := "config: unknown option ouptuts, did you mean outputs?; unknown option pipeline.bufer, did you mean pipeline.buffer?"
+	errs := c.Check()
+	if len(errs) == 0 || errs[0].Error() != want {
+		t.Errorf("got %v, want %q", errs, want)
+	}
+}
+
+func TestCheck_destDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	missing := filepath.Join(dir, "not-mounted")
+
+	tests := []struct {
+		name    string
+		outputs []map[string]interface{}
+		dest    string
+		want    string
+	}{
+		{
+			name:    "created",
+			outputs: []map[string]interface{}{{"name": "path-mover"}},
+			dest:    missing,
+		},
+		{
+			name:    "exists",
+			outputs: []map[string]interface{}{{"name": "path-mover", "create-dirs": false}},
+			dest:    dir,
+		},
+		{
+			name:    "missing",
+			outputs: []map[string]interface{}{{"name": "path-mover", "create-dirs": false}},
+			dest:    missing,
+			want:    "processors.post[0] (tv-path-solver): tv_destination: stat " + missing,
+		},
+		{
+			name:    "s3",
+			outputs: []map[string]interface{}{{"name": "s3-mover", "bucket": "media"}},
+			dest:    "library",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewSort(context.TODO())
+			c.Outputs = tt.outputs
+			c.Processors[processor.Post] = []map[string]interface{}{{"name": "tv-path-solver", "dest-dir": tt.dest}}
+			got := ""
+			for _, err := range c.Check() {
+				if strings.HasPrefix(err.Error(), "processors") {
+					got = err.Error()
+				}
+			}
+			if !strings.HasPrefix(got, tt.want) || (tt.want == "") != (got == "") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLKeys(t *testing.T) {
+	var dep pipeline.Dependency
+	in := map[interface{}]interface{}{"after": []interface{}{"path-mover"}, true: []interface{}{"failed"}}
+	if err := decode(in, &dep); err != nil {
+		t.Fatal(err)
+	}
+	if len(dep.On) != 1 || dep.On[0] != "failed" {
+		t.Errorf("got on %v, want [failed]", dep.On)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package external runs plugins as separate executables that speak a JSON
+lines protocol over their stdin and stdout, so that plugins can be written in
+any language and shipped without rebuilding pachinko.
+
+Each line is a Message. pachinko starts the executable and sends a hello
+with the kind of plugin it is run as and its config, and the plugin replies
+with a hello of its own. Then items are streamed: pachinko sends the items
+of the datastream to processors and outputs, and closes stdin at the end of
+it, and inputs and processors send items back until they exit. Any plugin can
+send log messages, and an error message to fail the handshake. Lines written
+to stderr are logged.
+*/
+package external
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Version is the version of the protocol.
+const Version = 1
+
+// Kind is the kind of plugin that the executable is run as.
+type Kind string
+
+const (
+	Input     Kind = "input"
+	Processor Kind = "processor"
+	Output    Kind = "output"
+)
+
+// Message types.
+const (
+	Hello = "hello"
+	Item  = "item"
+	Log   = "log"
+	Error = "error"
+)
+
+// Message is a line of the protocol.
+type Message struct {
+	Type string `json:"type"`
+	// Version of the protocol, in hellos
+	Version int `json:"version,omitempty"`
+	// Kind of plugin, in the hello from pachinko
+	Kind Kind `json:"kind,omitempty"`
+	// Config of the plugin, in the hello from pachinko
+	Config map[string]interface{} `json:"config,omitempty"`
+	// DryRun is set in the hello from pachinko to outputs in a dry run
+	DryRun bool `json:"dry-run,omitempty"`
+	// Item, in items
+	Item *types.Item `json:"item,omitempty"`
+	// Level of log messages, defaults to info
+	Level string `json:"level,omitempty"`
+	// Message of log and error messages
+	Message string `json:"message,omitempty"`
+}
+
+// Command is the configuration of an external plugin, embedded in the
+// plugins that run one.
+type Command struct {
+	// Command is the executable and its arguments
+	Command []string `mapstructure:"command" description:"the executable and its arguments"`
+	// Env are variables added to the environment of the executable
+	Env map[string]string `mapstructure:"env" description:"variables added to the environment of the executable"`
+	// Config is sent to the executable in the hello
+	Config map[string]interface{} `mapstructure:"config" description:"sent to the executable in the hello"`
+	// Timeout to wait for the executable to reply to the hello
+	Timeout time.Duration `mapstructure:"timeout" description:"time to wait for the executable to reply to the hello"`
+}
+
+// Process is a running external plugin.
+type Process struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	enc    *json.Encoder
+	dec    *json.Decoder
+	stderr sync.WaitGroup
+}
+
+// Start starts the executable and sends it the hello, returning once it has
+// replied. The executable is killed when the context is done.
+func (c *Command) Start(ctx context.Context, name string, kind Kind, dryRun bool) (*Process, error) {
+	if len(c.Command) == 0 {
+		return nil, errors.Errorf("%s: command must be set", name)
+	}
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Env = os.Environ()
+	for k, v := range c.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "%s: error starting %s", name, c.Command[0])
+	}
+	p := &Process{
+		name:  name,
+		cmd:   cmd,
+		stdin: stdin,
+		enc:   json.NewEncoder(stdin),
+		dec:   json.NewDecoder(bufio.NewReader(stdout)),
+	}
+	p.stderr.Add(1)
+	go func() {
+		defer p.stderr.Done()
+		s := bufio.NewScanner(stderr)
+		for s.Scan() {
+			log.Infof("%s: %s", name, s.Text())
+		}
+	}()
+
+	if err := p.handshake(kind, c.Config, dryRun, c.Timeout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = p.Wait()
+		return nil, err
+	}
+	log.Debugf("%s: started %s as %s", name, c.Command[0], kind)
+	return p, nil
+}
+
+func (p *Process) handshake(kind Kind, config map[string]interface{}, dryRun bool, timeout time.Duration) error {
+	if err := p.enc.Encode(&Message{Type: Hello, Version: Version, Kind: kind, Config: config, DryRun: dryRun}); err != nil {
+		return errors.Wrapf(err, "%s: error sending hello", p.name)
+	}
+	reply := make(chan error, 1)
+	go func() {
+		for {
+			var m Message
+			if err := p.dec.Decode(&m); err != nil {
+				reply <- errors.Wrapf(err, "%s: error reading hello", p.name)
+				return
+			}
+			switch m.Type {
+			case Log:
+				p.log(&m)
+			case Error:
+				reply <- errors.Errorf("%s: %s", p.name, m.Message)
+				return
+			case Hello:
+				if m.Version != Version {
+					reply <- errors.Errorf("%s: unsupported protocol version %d, want %d", p.name, m.Version, Version)
+					return
+				}
+				reply <- nil
+				return
+			default:
+				reply <- errors.Errorf("%s: expected hello, got %s", p.name, m.Type)
+				return
+			}
+		}
+	}()
+	if timeout <= 0 {
+		return <-reply
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-time.After(timeout):
+		return errors.Errorf("%s: timed out waiting for hello", p.name)
+	}
+}
+
+func (p *Process) log(m *Message) {
+	level, err := log.ParseLevel(m.Level)
+	if err != nil {
+		level = log.InfoLevel
+	}
+	log.StandardLogger().Logf(level, "%s: %s", p.name, m.Message)
+}
+
+// Send sends the item to the executable.
+func (p *Process) Send(m types.Item) error {
+	return p.enc.Encode(&Message{Type: Item, Item: &m})
+}
+
+// CloseSend closes the stdin of the executable, at the end of the
+// datastream.
+func (p *Process) CloseSend() error {
+	return p.stdin.Close()
+}
+
+// Receive calls the func with each item that the executable sends, and logs
+// its log and error messages, until it closes its stdout.
+func (p *Process) Receive(f func(types.Item)) {
+	for {
+		var m Message
+		if err := p.dec.Decode(&m); err != nil {
+			if err != io.EOF {
+				log.Errorf("%s: error reading message: %s", p.name, err)
+			}
+			return
+		}
+		switch m.Type {
+		case Item:
+			if m.Item == nil {
+				log.Warnf("%s: item message without an item", p.name)
+				continue
+			}
+			f(*m.Item)
+		case Log:
+			p.log(&m)
+		case Error:
+			log.Errorf("%s: %s", p.name, m.Message)
+		default:
+			log.Warnf("%s: unexpected %s message", p.name, m.Type)
+		}
+	}
+}
+
+// Wait waits for the executable to exit.
+func (p *Process) Wait() error {
+	p.stderr.Wait()
+	if err := p.cmd.Wait(); err != nil {
+		return errors.Wrapf(err, "%s", p.name)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package external
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+// TestMain runs the test binary as the plugin when the helper env is set.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("PACHINKO_EXTERNAL_HELPER"); mode != "" {
+		helper(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// helper is a plugin that moves items in to the dest of its config and drops
+// text files.
+func helper(mode string) {
+	in := json.NewDecoder(bufio.NewReader(os.Stdin))
+	out := json.NewEncoder(os.Stdout)
+	var hello Message
+	if err := in.Decode(&hello); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	switch mode {
+	case "error":
+		_ = out.Encode(&Message{Type: Error, Message: "bad config"})
+		return
+	case "version":
+		_ = out.Encode(&Message{Type: Hello, Version: Version + 1})
+		return
+	case "hang":
+		time.Sleep(time.Minute)
+		return
+	}
+	fmt.Fprintln(os.Stderr, "started")
+	_ = out.Encode(&Message{Type: Log, Level: "debug", Message: "config " + fmt.Sprint(hello.Config)})
+	_ = out.Encode(&Message{Type: Hello, Version: Version})
+	dest, _ := hello.Config["dest"].(string)
+	if hello.Kind == Input {
+		for _, name := range []string{"a.mkv", "b.mkv"} {
+			_ = out.Encode(&Message{Type: Item, Item: &types.Item{SourcePath: name, FileType: types.File}})
+		}
+		return
+	}
+	for {
+		var m Message
+		if err := in.Decode(&m); err != nil {
+			return
+		}
+		if strings.HasSuffix(m.Item.SourcePath, ".txt") {
+			continue
+		}
+		m.Item.DestinationPath = filepath.Join(dest, m.Item.SourcePath)
+		_ = out.Encode(&m)
+	}
+}
+
+func command(mode string) *Command {
+	return &Command{
+		Command: []string{os.Args[0], "-test.run=^$"},
+		Env:     map[string]string{"PACHINKO_EXTERNAL_HELPER": mode},
+		Config:  map[string]interface{}{"dest": "/dest"},
+		Timeout: 5 * time.Second,
+	}
+}
+
+func TestProcess(t *testing.T) {
+	p, err := command("ok").Start(context.TODO(), "test", Processor, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []types.Item{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Receive(func(m types.Item) {
+			got = append(got, m)
+		})
+	}()
+	for _, name := range []string{"a.mkv", "b.txt", "c.mkv"} {
+		if err := p.Send(types.Item{SourcePath: name, FileType: types.File}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/dest/a.mkv", "/dest/c.mkv"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].DestinationPath != want[i] {
+			t.Errorf("got %s, want %s", got[i].DestinationPath, want[i])
+		}
+	}
+}
+
+func TestProcess_input(t *testing.T) {
+	p, err := command("ok").Start(context.TODO(), "test", Input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	p.Receive(func(types.Item) { count++ })
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("got %d items, want 2", count)
+	}
+}
+
+func TestCommand_Start(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"error", "test: bad config"},
+		{"version", "test: unsupported protocol version 2, want 1"},
+		{"hang", "test: timed out waiting for hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			c := command(tt.mode)
+			c.Timeout = time.Second
+			_, err := c.Start(context.TODO(), "test", Processor, false)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got %v, want %s", err, tt.want)
+			}
+		})
+	}
+	if _, err := (&Command{}).Start(context.TODO(), "test", Processor, false); err == nil {
+		t.Error("expected an error without a command")
+	}
+}
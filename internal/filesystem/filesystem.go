@@ -0,0 +1,111 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package filesystem abstracts the filesystem operations of the plugins that
+walk, read, and move files so that they can work on the local filesystem or
+on a remote one over SFTP.
+
+Paths on remote filesystems are represented in the datastream as URLs, like
+sftp://user@host:22/downloads/file.mkv, so that they can't be confused with
+local paths by plugins that aren't configured for that filesystem.
+*/
+package filesystem
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Type is a filesystem implementation.
+type Type string
+
+const (
+	Local Type = "local"
+	SFTP  Type = "sftp"
+)
+
+// Config selects and configures a filesystem.
+type Config struct {
+	// Type of the filesystem, local (default) or sftp
+	Type Type `mapstructure:"type" description:"the type of the filesystem" enum:",local,sftp"`
+	// Host:port of the sftp server, the port defaults to 22
+	Host string `mapstructure:"host" description:"host:port of the sftp server, the port defaults to 22"`
+	// User to log in to the sftp server as
+	User string `mapstructure:"user" description:"user to log in to the sftp server as"`
+	// Password to log in with
+	Password string `mapstructure:"password" description:"password to log in with"`
+	// KeyFile is a private key to log in with
+	KeyFile string `mapstructure:"key-file" description:"private key to log in with"`
+	// KnownHosts file to verify the server's host key against, defaults to
+	// ~/.ssh/known_hosts
+	KnownHosts string `mapstructure:"known-hosts" description:"file to verify the server's host key against, defaults to ~/.ssh/known_hosts"`
+	// InsecureIgnoreHostKey disables host key verification
+	InsecureIgnoreHostKey bool `mapstructure:"insecure-ignore-host-key" description:"disable host key verification"`
+}
+
+// FS is a filesystem.
+type FS interface {
+	// ReadDir lists the directory without following symlinks.
+	ReadDir(name string) ([]os.FileInfo, error)
+	// Stat describes the file, following symlinks.
+	Stat(name string) (os.FileInfo, error)
+	// RealPath resolves all symlinks in the path.
+	RealPath(name string) (string, error)
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(name string) error
+	Rename(oldname, newname string) error
+	Remove(name string) error
+	// URL returns the path as it is represented in the datastream.
+	URL(name string) string
+	// Path returns the path on the filesystem from its datastream
+	// representation, and false if the path is not on this filesystem.
+	Path(url string) (string, bool)
+	// Same is true if the other FS is the same filesystem.
+	Same(FS) bool
+	Close() error
+}
+
+// New creates the configured filesystem.
+func New(cfg Config) (FS, error) {
+	switch cfg.Type {
+	case "", Local:
+		return &local{}, nil
+	case SFTP:
+		return dialSFTP(cfg)
+	}
+	return nil, errors.Errorf("unknown filesystem type %s", cfg.Type)
+}
+
+// IsURL is true if the path is a URL of a remote filesystem.
+func IsURL(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+// Copy copies the file from one filesystem to another.
+func Copy(dst FS, dstName string, src FS, srcName string) error {
+	in, err := src.Open(srcName)
+	if err != nil {
+		return errors.Errorf("error opening src: %s", err)
+	}
+	defer in.Close()
+
+	out, err := dst.Create(dstName)
+	if err != nil {
+		return errors.Errorf("error opening dest: %s", err)
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return errors.Errorf("error writing dest: %s", err)
+	}
+	return out.Close()
+}
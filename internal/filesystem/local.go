@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package filesystem
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// local is the local filesystem.
+type local struct{}
+
+func (*local) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+func (*local) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (*local) RealPath(name string) (string, error) {
+	return filepath.EvalSymlinks(name)
+}
+
+func (*local) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (*local) Create(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+func (*local) MkdirAll(name string) error {
+	return os.MkdirAll(name, os.ModePerm)
+}
+
+func (*local) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+func (*local) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (*local) URL(name string) string {
+	return name
+}
+
+func (*local) Path(url string) (string, bool) {
+	return url, !IsURL(url)
+}
+
+func (*local) Same(other FS) bool {
+	_, ok := other.(*local)
+	return ok
+}
+
+func (*local) Close() error {
+	return nil
+}
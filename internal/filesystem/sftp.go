@@ -0,0 +1,205 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package filesystem
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// maxLinks bounds symlink resolution.
+const maxLinks = 40
+
+// sftpFS is a remote filesystem accessed over SFTP.
+type sftpFS struct {
+	client *sftp.Client
+	conn   io.Closer
+	// prefix is the URL of the root of the filesystem
+	prefix string
+}
+
+func authMethods(cfg Config) ([]ssh.AuthMethod, error) {
+	methods := []ssh.AuthMethod{}
+	if cfg.KeyFile != "" {
+		file, err := homedir.Expand(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing key %s", cfg.KeyFile)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		methods = append(methods, ssh.Password(cfg.Password))
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("sftp: one of password or key-file must be set")
+	}
+	return methods, nil
+}
+
+func hostKeyCallback(cfg Config) (ssh.HostKeyCallback, error) {
+	if cfg.InsecureIgnoreHostKey {
+		// nolint: gosec
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	file := cfg.KnownHosts
+	if file == "" {
+		file = "~/.ssh/known_hosts"
+	}
+	file, err := homedir.Expand(file)
+	if err != nil {
+		return nil, err
+	}
+	return knownhosts.New(file)
+}
+
+func dialSFTP(cfg Config) (FS, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("sftp: host must be set")
+	}
+	host := cfg.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+	auth, err := authMethods(cfg)
+	if err != nil {
+		return nil, err
+	}
+	callback, err := hostKeyCallback(cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            auth,
+		HostKeyCallback: callback,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "sftp: error connecting to %s", host)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newSFTP(client, conn, cfg.User, host), nil
+}
+
+func newSFTP(client *sftp.Client, conn io.Closer, user, host string) *sftpFS {
+	prefix := "sftp://" + host
+	if user != "" {
+		prefix = "sftp://" + user + "@" + host
+	}
+	return &sftpFS{client: client, conn: conn, prefix: prefix}
+}
+
+func (s *sftpFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return s.client.ReadDir(name)
+}
+
+func (s *sftpFS) Stat(name string) (os.FileInfo, error) {
+	return s.client.Stat(name)
+}
+
+// RealPath resolves the symlinks in each component of the path.
+func (s *sftpFS) RealPath(name string) (string, error) {
+	resolved := "/"
+	rest := strings.Split(strings.Trim(path.Clean(name), "/"), "/")
+	for links := 0; len(rest) > 0; {
+		next := path.Join(resolved, rest[0])
+		rest = rest[1:]
+		info, err := s.client.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > maxLinks {
+			return "", errors.Errorf("too many links resolving %s", name)
+		}
+		target, err := s.client.ReadLink(next)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(strings.Trim(target, "/"), "/"), rest...)
+	}
+	return resolved, nil
+}
+
+func (s *sftpFS) Open(name string) (io.ReadCloser, error) {
+	return s.client.Open(name)
+}
+
+func (s *sftpFS) Create(name string) (io.WriteCloser, error) {
+	return s.client.Create(name)
+}
+
+func (s *sftpFS) MkdirAll(name string) error {
+	return s.client.MkdirAll(name)
+}
+
+// Rename prefers the posix rename extension, which replaces existing files
+// like a local rename does.
+func (s *sftpFS) Rename(oldname, newname string) error {
+	if err := s.client.PosixRename(oldname, newname); err != nil {
+		return s.client.Rename(oldname, newname)
+	}
+	return nil
+}
+
+func (s *sftpFS) Remove(name string) error {
+	return s.client.Remove(name)
+}
+
+func (s *sftpFS) URL(name string) string {
+	return s.prefix + name
+}
+
+func (s *sftpFS) Path(url string) (string, bool) {
+	if !strings.HasPrefix(url, s.prefix+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(url, s.prefix), true
+}
+
+func (s *sftpFS) Same(other FS) bool {
+	o, ok := other.(*sftpFS)
+	return ok && o.prefix == s.prefix
+}
+
+func (s *sftpFS) Close() error {
+	err := s.client.Close()
+	if s.conn != nil {
+		if cerr := s.conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package filesystem
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// testSFTP serves the local filesystem over an in-process sftp connection.
+func testSFTP(t *testing.T) *sftpFS {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	server, err := sftp.NewServer(pipeConn{sr, sw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		// the client waits for the server to hang up when it closes
+		server.Serve() // nolint: errcheck
+		sw.Close()
+	}()
+	client, err := sftp.NewClientPipe(cr, cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newSFTP(client, nil, "user", "example.com:22")
+}
+
+func TestSFTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sftp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.mkv"), []byte("video"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "real"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := testSFTP(t)
+	defer fs.Close()
+
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Errorf("got %d entries, want 3", len(infos))
+	}
+
+	real, err := fs.RealPath(filepath.Join(dir, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "real"); real != want {
+		t.Errorf("RealPath got %s, want %s", real, want)
+	}
+
+	url := fs.URL(filepath.Join(dir, "a.mkv"))
+	if want := "sftp://user@example.com:22" + dir + "/a.mkv"; url != want {
+		t.Errorf("URL got %s, want %s", url, want)
+	}
+	if path, ok := fs.Path(url); !ok || path != filepath.Join(dir, "a.mkv") {
+		t.Errorf("Path got %s %v", path, ok)
+	}
+	if _, ok := fs.Path(filepath.Join(dir, "a.mkv")); ok {
+		t.Error("Path accepted a local path")
+	}
+
+	// copy from the remote to the local filesystem
+	if err := Copy(&local{}, filepath.Join(dir, "b.mkv"), fs, filepath.Join(dir, "a.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "b.mkv")); string(b) != "video" {
+		t.Errorf("copy got %q", b)
+	}
+
+	if err := fs.Rename(filepath.Join(dir, "b.mkv"), filepath.Join(dir, "real", "b.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "real", "b.mkv")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package library is a persistent index of the items that pachinko has
+sorted, stored in an embedded bbolt database, and queries over it.
+*/
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DefaultPath of the database.
+	DefaultPath = "/etc/pachinko/library.db"
+
+	// hashChunk is the size of the head and tail of a file that are hashed.
+	hashChunk = 1 << 20
+)
+
+var itemsBucket = []byte("items")
+
+// Record is an item in the library, keyed by its destination.
+type Record struct {
+	Item types.Item `json:"item"`
+	// Hash of the file, see Hash
+	Hash string `json:"hash"`
+	// Sorted is when the item was moved in to the library
+	Sorted time.Time `json:"sorted"`
+}
+
+// DB is the library database.
+type DB struct {
+	db *bolt.DB
+}
+
+// Open opens, or creates, the database.
+func Open(path string, readOnly bool) (*DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening library %s", path)
+	}
+	if !readOnly {
+		if err := db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(itemsBucket)
+			return err
+		}); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &DB{db: db}, nil
+}
+
+// Close closes the database.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Put records the item at its destination. If the item was moved from
+// somewhere else in the library, the old record is removed.
+func (d *DB) Put(r Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(itemsBucket)
+		if err := bucket.Delete([]byte(r.Item.SourcePath)); err != nil {
+			return err
+		}
+		return bucket.Put([]byte(r.Item.DestinationPath), b)
+	})
+}
+
+// Delete removes the record of the path.
+func (d *DB) Delete(path string) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).Delete([]byte(path))
+	})
+}
+
+// Records calls f for each record, in order of destination.
+func (d *DB) Records(f func(Record) error) error {
+	return d.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(itemsBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			r := Record{}
+			if err := json.Unmarshal(v, &r); err != nil {
+				return errors.Wrapf(err, "bad record %s", k)
+			}
+			return f(r)
+		})
+	})
+}
+
+// Hash is a quick content hash of the file: the sha256 of its size and its
+// first and last MiB, which is enough to identify media files without
+// reading them entirely.
+func Hash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if err := binary.Write(h, binary.BigEndian, info.Size()); err != nil {
+		return "", err
+	}
+	if _, err := io.CopyN(h, f, hashChunk); err != nil && err != io.EOF {
+		return "", err
+	}
+	if info.Size() > 2*hashChunk {
+		if _, err := f.Seek(-hashChunk, io.SeekEnd); err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Season is the episodes of a season of a show in the library.
+type Season struct {
+	Number   int
+	Episodes []int
+}
+
+// Missing returns the episode numbers missing from the season, between the
+// first and the highest episode in the library.
+func (s *Season) Missing() []int {
+	missing := []int{}
+	have := map[int]bool{}
+	max := 0
+	for _, e := range s.Episodes {
+		have[e] = true
+		if e > max {
+			max = e
+		}
+	}
+	for e := 1; e < max; e++ {
+		if !have[e] {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
+
+// Show is a show in the library.
+type Show struct {
+	Name    string
+	Seasons []*Season
+}
+
+// Shows returns the shows in the library, in order of name.
+func (d *DB) Shows() ([]*Show, error) {
+	shows := map[string]map[int]*Season{}
+	if err := d.Records(func(r Record) error {
+		m := r.Item
+		if m.MediaType != tv.TV || m.TVMetadata.Name == "" {
+			return nil
+		}
+		seasons, ok := shows[m.TVMetadata.Name]
+		if !ok {
+			seasons = map[int]*Season{}
+			shows[m.TVMetadata.Name] = seasons
+		}
+		n := m.TVMetadata.Season.Number
+		if _, ok := seasons[n]; !ok {
+			seasons[n] = &Season{Number: n}
+		}
+		seasons[n].Episodes = append(seasons[n].Episodes, m.TVMetadata.Episode.Number)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	list := []*Show{}
+	for name, seasons := range shows {
+		show := &Show{Name: name}
+		for _, s := range seasons {
+			sort.Ints(s.Episodes)
+			show.Seasons = append(show.Seasons, s)
+		}
+		sort.Slice(show.Seasons, func(i, j int) bool { return show.Seasons[i].Number < show.Seasons[j].Number })
+		list = append(list, show)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Duplicates returns the records of movies that are in the library more
+// than once, grouped by their tmdb id, or title and year if they have none.
+func (d *DB) Duplicates() ([][]Record, error) {
+	groups := map[string][]Record{}
+	keys := []string{}
+	if err := d.Records(func(r Record) error {
+		m := r.Item
+		if m.MediaType != movie.Movie {
+			return nil
+		}
+		key := "tmdb:" + m.Identifiers["tmdb"]
+		if m.Identifiers["tmdb"] == "" {
+			key = strings.ToLower(m.MovieMetadata.Title) + ":" + strconv.Itoa(m.MovieMetadata.ReleaseYear)
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], r)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	dupes := [][]Record{}
+	for _, k := range keys {
+		if len(groups[k]) > 1 {
+			dupes = append(dupes, groups[k])
+		}
+	}
+	return dupes, nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package library
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+func episode(name string, season, ep int) Record {
+	m := types.Item{
+		SourcePath:      fmt.Sprintf("/src/%s S%02dE%02d.mkv", name, season, ep),
+		DestinationPath: fmt.Sprintf("/tv/%s/Season %02d/%s S%02dE%02d.mkv", name, season, name, season, ep),
+		MediaType:       tv.TV,
+	}
+	m.TVMetadata.Name = name
+	m.TVMetadata.Season.Number = season
+	m.TVMetadata.Episode.Number = ep
+	return Record{Item: m}
+}
+
+func film(dest, title string, year int, tmdb string) Record {
+	m := types.Item{
+		SourcePath:      "/src/" + dest,
+		DestinationPath: "/movies/" + dest,
+		MediaType:       movie.Movie,
+		Identifiers:     map[string]string{},
+	}
+	if tmdb != "" {
+		m.Identifiers["tmdb"] = tmdb
+	}
+	m.MovieMetadata.Title = title
+	m.MovieMetadata.ReleaseYear = year
+	return Record{Item: m}
+}
+
+func TestDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := Open(filepath.Join(dir, "library.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	records := []Record{
+		episode("Mr Robot", 1, 1),
+		episode("Mr Robot", 1, 2),
+		episode("Mr Robot", 1, 5),
+		episode("Mr Robot", 2, 1),
+		episode("Fargo", 1, 3),
+		film("a.mkv", "Heat", 1995, "949"),
+		film("b.mkv", "Heat", 1995, "949"),
+		film("c.mkv", "Alien", 1979, ""),
+		film("d.mkv", "alien", 1979, ""),
+		film("e.mkv", "Alien", 1986, ""),
+	}
+	for _, r := range records {
+		if err := db.Put(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// re-sorting an item replaces its old record
+	moved := episode("Fargo", 1, 3)
+	moved.Item.SourcePath = records[4].Item.DestinationPath
+	moved.Item.DestinationPath = "/tv/Fargo/Season 01/Fargo S01E03.mkv"
+	if err := db.Put(moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(records[3].Item.DestinationPath); err != nil {
+		t.Fatal(err)
+	}
+
+	shows, err := db.Shows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shows) != 2 || shows[0].Name != "Fargo" || shows[1].Name != "Mr Robot" {
+		t.Fatalf("got shows %+v", shows)
+	}
+	if len(shows[0].Seasons) != 1 || !reflect.DeepEqual(shows[0].Seasons[0].Episodes, []int{3}) {
+		t.Errorf("got Fargo seasons %+v", shows[0].Seasons[0])
+	}
+	if got := shows[0].Seasons[0].Missing(); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got Fargo missing %v", got)
+	}
+	if len(shows[1].Seasons) != 1 {
+		t.Fatalf("got Mr Robot seasons %+v", shows[1].Seasons)
+	}
+	if got := shows[1].Seasons[0].Missing(); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf("got Mr Robot missing %v", got)
+	}
+
+	dupes, err := db.Duplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dupes) != 2 || len(dupes[0]) != 2 || len(dupes[1]) != 2 {
+		t.Fatalf("got duplicates %+v", dupes)
+	}
+	for _, group := range dupes {
+		if group[0].Item.MovieMetadata.ReleaseYear != group[1].Item.MovieMetadata.ReleaseYear {
+			t.Errorf("got mismatched group %+v", group)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, b []byte) string {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	big := make([]byte, 3*hashChunk)
+	a := write("a", big)
+	b := write("b", big)
+	big[len(big)-1] = 1
+	c := write("c", big)
+	big[len(big)-1] = 0
+	big[hashChunk+1] = 1
+	d := write("d", big)
+
+	hashes := map[string]string{}
+	for _, p := range []string{a, b, c, d} {
+		if hashes[p], err = Hash(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hashes[a] != hashes[b] {
+		t.Error("expected identical files to hash the same")
+	}
+	if hashes[a] == hashes[c] {
+		t.Error("expected a change in the tail to change the hash")
+	}
+	// only the head and tail are hashed
+	if hashes[a] != hashes[d] {
+		t.Error("expected a change in the middle not to change the hash")
+	}
+}
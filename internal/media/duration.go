@@ -0,0 +1,241 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package media provides lightweight inspection of media file contents
+without depending on external tools like ffprobe.
+*/
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupported is returned when the container format is not recognized.
+var ErrUnsupported = errors.New("unsupported container format")
+
+// Duration reads the playback duration from the container header of the
+// file at path. MP4/MOV (mvhd) and Matroska/WebM (Segment Info) are
+// supported.
+func Duration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return ReadDuration(f)
+}
+
+// ReadDuration reads the playback duration from the container header.
+func ReadDuration(r io.ReadSeeker) (time.Duration, error) {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	switch {
+	case binary.BigEndian.Uint32(head) == ebmlHeaderID:
+		return mkvDuration(r)
+	case string(head[4:8]) == "ftyp" || string(head[4:8]) == "moov":
+		return mp4Duration(r)
+	}
+	return 0, ErrUnsupported
+}
+
+// mp4Duration finds the moov/mvhd box and computes duration from its
+// timescale.
+func mp4Duration(r io.ReadSeeker) (time.Duration, error) {
+	if _, err := findBox(r, "moov", -1); err != nil {
+		return 0, err
+	}
+	size, err := findBox(r, "mvhd", -1)
+	if err != nil {
+		return 0, err
+	}
+	// only the version and time fields are needed
+	if size > 32 {
+		size = 32
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+	var timescale, duration uint64
+	switch {
+	case len(b) >= 32 && b[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(b[20:24]))
+		duration = binary.BigEndian.Uint64(b[24:32])
+	case len(b) >= 20:
+		timescale = uint64(binary.BigEndian.Uint32(b[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(b[16:20]))
+	default:
+		return 0, errors.New("mvhd box too short")
+	}
+	if timescale == 0 {
+		return 0, errors.New("mvhd timescale is 0")
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// findBox scans sibling boxes from the current offset until it finds the
+// named box, leaving the reader at the start of its payload and returning
+// the payload size. limit bounds the scan to the parent's payload, -1 is
+// unbounded.
+func findBox(r io.ReadSeeker, name string, limit int64) (int64, error) {
+	head := make([]byte, 8)
+	for read := int64(0); limit < 0 || read < limit; {
+		if _, err := io.ReadFull(r, head); err != nil {
+			if err == io.EOF {
+				return 0, errors.Errorf("no %s box found", name)
+			}
+			return 0, err
+		}
+		size := int64(binary.BigEndian.Uint32(head[:4]))
+		hlen := int64(8)
+		if size == 1 {
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(r, ext); err != nil {
+				return 0, err
+			}
+			size = int64(binary.BigEndian.Uint64(ext))
+			hlen = 16
+		}
+		if string(head[4:8]) == name {
+			if size == 0 {
+				return math.MaxInt32, nil
+			}
+			return size - hlen, nil
+		}
+		if size < hlen {
+			return 0, errors.Errorf("bad box size %d for %s", size, head[4:8])
+		}
+		if _, err := r.Seek(size-hlen, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+		read += size
+	}
+	return 0, errors.Errorf("no %s box found", name)
+}
+
+const (
+	ebmlHeaderID     = 0x1A45DFA3
+	mkvSegmentID     = 0x18538067
+	mkvInfoID        = 0x1549A966
+	mkvTimecodeScale = 0x2AD7B1
+	mkvDurationID    = 0x4489
+)
+
+// mkvDuration reads the Segment/Info/Duration element, scaled by the
+// Segment/Info/TimecodeScale.
+func mkvDuration(r io.ReadSeeker) (time.Duration, error) {
+	// skip the EBML header
+	if _, size, err := readElement(r); err != nil {
+		return 0, err
+	} else if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	if id, _, err := readElement(r); err != nil {
+		return 0, err
+	} else if id != mkvSegmentID {
+		return 0, errors.New("no matroska segment found")
+	}
+	for {
+		id, size, err := readElement(r)
+		if err != nil {
+			return 0, err
+		}
+		if id != mkvInfoID {
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		scale := uint64(1000000)
+		duration := -1.0
+		for read := int64(0); read < size; {
+			start, _ := r.Seek(0, io.SeekCurrent)
+			id, esize, err := readElement(r)
+			if err != nil {
+				return 0, err
+			}
+			b := make([]byte, esize)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return 0, err
+			}
+			switch id {
+			case mkvTimecodeScale:
+				scale = 0
+				for _, c := range b {
+					scale = scale<<8 | uint64(c)
+				}
+			case mkvDurationID:
+				switch len(b) {
+				case 4:
+					duration = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+				case 8:
+					duration = math.Float64frombits(binary.BigEndian.Uint64(b))
+				}
+			}
+			end, _ := r.Seek(0, io.SeekCurrent)
+			read += end - start
+		}
+		if duration < 0 {
+			return 0, errors.New("matroska info has no duration")
+		}
+		return time.Duration(duration * float64(scale)), nil
+	}
+}
+
+// readElement reads an EBML element ID and data size.
+func readElement(r io.Reader) (uint64, int64, error) {
+	id, _, err := readVint(r, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, _, err := readVint(r, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, int64(size), nil
+}
+
+// readVint reads an EBML variable length integer. IDs keep their length
+// marker bit, sizes do not.
+func readVint(r io.Reader, keepMarker bool) (uint64, int, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, 0, err
+	}
+	length := 1
+	for mask := byte(0x80); length <= 8 && b[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, 0, errors.New("invalid ebml vint")
+	}
+	value := uint64(b[0])
+	if !keepMarker {
+		value &= uint64(0xFF >> uint(length))
+	}
+	rest := make([]byte, length-1)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return 0, 0, err
+	}
+	for _, c := range rest {
+		value = value<<8 | uint64(c)
+	}
+	return value, length, nil
+}
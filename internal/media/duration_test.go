@@ -0,0 +1,83 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func box(name string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(len(body)+8))
+	copy(b[4:], name)
+	return append(b, body...)
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func TestReadDuration_mp4(t *testing.T) {
+	mvhd := box("mvhd", []byte{0, 0, 0, 0}, u32(0), u32(0), u32(1000), u32(90500), make([]byte, 80))
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("isom"), u32(0)),
+		box("free", make([]byte, 16)),
+		box("moov", mvhd, box("trak")),
+	}, nil)
+	got, err := ReadDuration(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 90500 * time.Millisecond; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func element(id []byte, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	// 8 byte size vint
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+	return bytes.Join([][]byte{id, size, body}, nil)
+}
+
+func TestReadDuration_mkv(t *testing.T) {
+	duration := make([]byte, 8)
+	binary.BigEndian.PutUint64(duration, math.Float64bits(42000))
+	file := bytes.Join([][]byte{
+		element([]byte{0x1A, 0x45, 0xDF, 0xA3}, element([]byte{0x42, 0x82}, []byte("matroska"))),
+		element([]byte{0x18, 0x53, 0x80, 0x67},
+			element([]byte{0x11, 0x4D, 0x9B, 0x74}, make([]byte, 12)),
+			element([]byte{0x15, 0x49, 0xA9, 0x66},
+				element([]byte{0x2A, 0xD7, 0xB1}, []byte{0x0F, 0x42, 0x40}),
+				element([]byte{0x44, 0x89}, duration),
+			),
+		),
+	}, nil)
+	got, err := ReadDuration(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 42 * time.Second; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestReadDuration_unsupported(t *testing.T) {
+	if _, err := ReadDuration(bytes.NewReader([]byte("RIFF1234AVI LIST"))); err != ErrUnsupported {
+		t.Errorf("got %v, want %v", err, ErrUnsupported)
+	}
+}
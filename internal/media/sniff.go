@@ -0,0 +1,110 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package media
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+// sniffLen is enough to see three MPEG-TS packets.
+const sniffLen = 3*192 + 4
+
+type signature struct {
+	offset   int
+	magic    []byte
+	category types.Category
+}
+
+// signatures are checked in order, the first match wins.
+var signatures = []signature{
+	{0, []byte{0x1A, 0x45, 0xDF, 0xA3}, types.Video},             // EBML (mkv, webm)
+	{0, []byte{0x00, 0x00, 0x01, 0xBA}, types.Video},             // MPEG-PS
+	{0, []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66}, types.Video}, // ASF (wmv)
+	{0, []byte("FLV\x01"), types.Video},                          // flv
+	{8, []byte("AVI "), types.Video},                             // RIFF AVI
+	{8, []byte("WEBP"), types.Image},                             // RIFF WEBP
+	{0, []byte{0x89, 'P', 'N', 'G'}, types.Image},                // png
+	{0, []byte{0xFF, 0xD8, 0xFF}, types.Image},                   // jpeg
+	{0, []byte("GIF8"), types.Image},                             // gif
+	{0, []byte("PK\x03\x04"), types.Archive},                     // zip
+	{0, []byte("Rar!\x1A\x07"), types.Archive},                   // rar
+	{0, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, types.Archive}, // 7z
+	{0, []byte{0x1F, 0x8B}, types.Archive},                       // gzip
+	{257, []byte("ustar"), types.Archive},                        // tar
+}
+
+// imageBrands are the ISO base media file brands of still images, all other
+// brands are treated as video.
+var imageBrands = map[string]bool{
+	"avif": true,
+	"heic": true,
+	"heix": true,
+	"mif1": true,
+	"msf1": true,
+}
+
+// Sniff reads the start of the file at path and returns the Category
+// identified from its content, or types.Unknown.
+func Sniff(path string) (types.Category, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return types.Unknown, err
+	}
+	defer f.Close()
+	return SniffReader(f)
+}
+
+// SniffReader reads the start of the stream and returns the Category
+// identified from its content, or types.Unknown.
+func SniffReader(r io.Reader) (types.Category, error) {
+	b := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return types.Unknown, err
+	}
+	return sniff(b[:n]), nil
+}
+
+func sniff(b []byte) types.Category {
+	// ISO base media (mp4, mov, m4v, heic)
+	if len(b) >= 12 && string(b[4:8]) == "ftyp" {
+		if imageBrands[string(b[8:12])] {
+			return types.Image
+		}
+		return types.Video
+	}
+	// MPEG-TS has a sync byte every 188 bytes, M2TS every 192 after a
+	// 4 byte timestamp
+	for _, stride := range []struct{ offset, size int }{{0, 188}, {4, 192}} {
+		if isSynced(b, stride.offset, stride.size) {
+			return types.Video
+		}
+	}
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(b) >= end && bytes.Equal(b[sig.offset:end], sig.magic) {
+			return sig.category
+		}
+	}
+	return types.Unknown
+}
+
+func isSynced(b []byte, offset, size int) bool {
+	packets := 0
+	for i := offset; i < len(b); i += size {
+		if b[i] != 0x47 {
+			return false
+		}
+		packets++
+	}
+	return packets >= 3
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package media
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+func packets(offset, size, n int) []byte {
+	b := make([]byte, offset+size*n)
+	for i := 0; i < n; i++ {
+		b[offset+i*size] = 0x47
+	}
+	return b
+}
+
+func TestSniffReader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want types.Category
+	}{
+		{"mkv", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x01}, types.Video},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00"), types.Video},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), types.Image},
+		{"ts", packets(0, 188, 3), types.Video},
+		{"m2ts", packets(4, 192, 3), types.Video},
+		{"avi", []byte("RIFF\x00\x00\x00\x00AVI LIST"), types.Video},
+		{"png", []byte("\x89PNG\r\n\x1a\n"), types.Image},
+		{"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0}, types.Image},
+		{"zip", []byte("PK\x03\x04\x14\x00"), types.Archive},
+		{"rar", []byte("Rar!\x1A\x07\x01\x00"), types.Archive},
+		{"text", []byte("1\n00:00:01,000 --> 00:00:02,000\nhello\n"), types.Unknown},
+		{"empty", []byte{}, types.Unknown},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SniffReader(bytes.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,7 +10,9 @@ package pipeline
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
+	"github.com/pkg/errors"
 	"github.com/rbtr/pachinko/plugin/input"
 	"github.com/rbtr/pachinko/plugin/output"
 	"github.com/rbtr/pachinko/plugin/processor"
@@ -18,8 +20,71 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// ErrNoWaiters is returned by Wait if none of the inputs are Waiters.
+var ErrNoWaiters = errors.New("pipeline: no inputs wait for items")
+
 type Config struct {
-	Buffer int `mapstructure:"buffer"`
+	Buffer int `mapstructure:"buffer" description:"the number of items queued between plugins"`
+}
+
+// Dependency makes an output run after other outputs that are Reporters,
+// receiving the items that they had one of the outcomes with instead of the
+// datastream, with their Outcome set. It is configured alongside the
+// output's own options.
+type Dependency struct {
+	// After are the names of the outputs to run after
+	After []string `mapstructure:"after" description:"the names of the outputs to run after, on the items they report"`
+	// On are the outcomes of the items to receive, defaults to moved and
+	// deleted
+	On []string `mapstructure:"on" description:"the outcomes of the items to receive from the outputs it runs after, defaults to moved and deleted" enum:"moved,deleted,failed,skipped"`
+}
+
+// match tests if the outcome is one of the Dependency's.
+func (d *Dependency) match(outcome string) bool {
+	on := d.On
+	if len(on) == 0 {
+		on = []string{output.Moved, output.Deleted}
+	}
+	for _, o := range on {
+		if o == outcome {
+			return true
+		}
+	}
+	return false
+}
+
+// Options tune how an output receives the datastream. They are configured
+// alongside the output's own options.
+type Options struct {
+	// Buffer is the number of items queued for the output before the
+	// datastream waits for it, defaults to the pipeline's buffer
+	Buffer int `mapstructure:"buffer" description:"the number of items queued for the output before the datastream waits for it, defaults to the pipeline's buffer"`
+	// Concurrency is the number of instances of the output, each receiving a
+	// share of the items, defaults to 1
+	Concurrency int `mapstructure:"concurrency" description:"the number of instances of the output, each receiving a share of the items, defaults to 1"`
+}
+
+// stage is an output in the pipeline, with one or more instances.
+type stage struct {
+	Dependency
+	Options
+	name      string
+	instances []output.Output
+	in        chan types.Item
+	// upstream is done when the outputs that this stage runs after are
+	upstream sync.WaitGroup
+}
+
+// send sends the item to each of the stages, copying it for all but the last
+// so that none of them share its identifiers.
+func send(m types.Item, stages []*stage) {
+	for i, s := range stages {
+		if i < len(stages)-1 {
+			s.in <- m.Copy()
+		} else {
+			s.in <- m
+		}
+	}
 }
 
 // Pipeline pipeline.
@@ -27,7 +92,7 @@ type Pipeline struct {
 	Config
 	inputs     []input.Input
 	processors []processor.Processor
-	outputs    []output.Output
+	outputs    []*stage
 }
 
 func NewPipeline() *Pipeline {
@@ -74,39 +139,152 @@ func (p *Pipeline) runProcessors(ctx context.Context, source, sink chan types.It
 	log.Debug("pipeline: processors finished")
 }
 
-func (p *Pipeline) runOutputs(ctx context.Context, source chan types.Item) {
-	sinks := []chan<- types.Item{}
+// runOutputs broadcasts the datastream to the outputs without dependencies
+// and the results of the Reporters to the outputs that run after them, and
+// returns the number of items that the Reporters failed, counting each
+// failure once, by the Reporter that first reported it.
+//
+// Each output has a bounded buffer. When it is full, the datastream waits
+// for the output to catch up, so the number of items in flight and of
+// goroutines doesn't grow with the size of the datastream.
+func (p *Pipeline) runOutputs(ctx context.Context, source chan types.Item) int64 {
+	var failed int64
 	var wg sync.WaitGroup
-	for _, output := range p.outputs {
+	roots := []*stage{}
+	dependents := map[*stage][]*stage{}
+	for _, s := range p.outputs {
+		buffer := s.Buffer
+		if buffer <= 0 {
+			buffer = p.Buffer
+		}
+		s.in = make(chan types.Item, buffer)
+		if len(s.After) == 0 {
+			roots = append(roots, s)
+		}
+		for _, up := range p.outputs {
+			for _, name := range s.After {
+				if up.name == name {
+					dependents[up] = append(dependents[up], s)
+					s.upstream.Add(1)
+				}
+			}
+		}
+	}
+
+	for _, s := range p.outputs {
+		var results chan output.Result
+		if _, ok := s.instances[0].(output.Reporter); ok {
+			results = make(chan output.Result, cap(s.in))
+			wg.Add(1)
+			go func(results <-chan output.Result, downstream []*stage) {
+				defer wg.Done()
+				for r := range results {
+					// items that failed upstream are only counted once
+					if r.Outcome == output.Failed && r.Item.Outcome != output.Failed {
+						atomic.AddInt64(&failed, 1)
+					}
+					matched := []*stage{}
+					for _, d := range downstream {
+						if d.match(r.Outcome) {
+							matched = append(matched, d)
+						}
+					}
+					r.Item.Outcome = r.Outcome
+					send(r.Item, matched)
+				}
+				for _, d := range downstream {
+					d.upstream.Done()
+				}
+			}(results, dependents[s])
+		}
+		if len(s.After) > 0 {
+			go func(s *stage) {
+				s.upstream.Wait()
+				close(s.in)
+			}(s)
+		}
+		var instances sync.WaitGroup
+		for _, o := range s.instances {
+			if results != nil {
+				o.(output.Reporter).Report(results)
+			}
+			instances.Add(1)
+			go func(_ context.Context, f func(<-chan types.Item), in <-chan types.Item) {
+				defer instances.Done()
+				f(in)
+			}(ctx, o.Receive, s.in)
+		}
 		wg.Add(1)
-		out := make(chan types.Item)
-		go func(_ context.Context, f func(<-chan types.Item), in <-chan types.Item) {
+		go func(results chan output.Result) {
 			defer wg.Done()
-			f(in)
-		}(ctx, output.Receive, out)
-		sinks = append(sinks, out)
+			instances.Wait()
+			if results != nil {
+				close(results)
+			}
+		}(results)
 	}
 
 	wg.Add(1)
-	go func(ctx context.Context, in <-chan types.Item, outs []chan<- types.Item) {
-		var wgOut sync.WaitGroup
+	go func(in <-chan types.Item, roots []*stage) {
 		defer wg.Done()
 		for m := range in {
-			for _, out := range outs {
-				wgOut.Add(1)
-				go func(_ context.Context, i types.Item, o chan<- types.Item) {
-					defer wgOut.Done()
-					o <- i
-				}(ctx, m, out)
-			}
+			// only the outputs that run after others know the outcome
+			m.Outcome = ""
+			send(m, roots)
 		}
-		wgOut.Wait()
-		for _, o := range outs {
-			close(o)
+		for _, s := range roots {
+			close(s.in)
 		}
-	}(ctx, source, sinks)
+	}(source, roots)
 	wg.Wait()
 	log.Debug("pipeline: outputs finished")
+	return failed
+}
+
+// validate checks that the outputs run after Reporters, and not after
+// themselves.
+func (p *Pipeline) validate() error {
+	named := map[string][]*stage{}
+	for _, s := range p.outputs {
+		if s.name != "" {
+			named[s.name] = append(named[s.name], s)
+		}
+	}
+	for _, s := range p.outputs {
+		for _, name := range s.After {
+			if len(named[name]) == 0 {
+				return errors.Errorf("pipeline: output %s runs after %s, which is not configured", s.name, name)
+			}
+			for _, up := range named[name] {
+				if _, ok := up.instances[0].(output.Reporter); !ok {
+					return errors.Errorf("pipeline: output %s runs after %s, which does not report outcomes", s.name, name)
+				}
+			}
+		}
+	}
+	// follow the dependencies from each output looking for it
+	var visit func(*stage, map[*stage]bool) bool
+	visit = func(s *stage, seen map[*stage]bool) bool {
+		if seen[s] {
+			return false
+		}
+		seen[s] = true
+		for _, name := range s.After {
+			for _, up := range named[name] {
+				if !visit(up, seen) {
+					return false
+				}
+			}
+		}
+		delete(seen, s)
+		return true
+	}
+	for _, s := range p.outputs {
+		if !visit(s, map[*stage]bool{}) {
+			return errors.Errorf("pipeline: output %s runs after itself", s.name)
+		}
+	}
+	return nil
 }
 
 func (p *Pipeline) WithInputs(inputs ...input.Input) {
@@ -118,11 +296,23 @@ func (p *Pipeline) WithProcessors(processors ...processor.Processor) {
 }
 
 func (p *Pipeline) WithOutputs(outputs ...output.Output) {
-	p.outputs = append(p.outputs, outputs...)
+	for _, o := range outputs {
+		p.outputs = append(p.outputs, &stage{instances: []output.Output{o}})
+	}
+}
+
+// WithOutput adds the instances of an output, with the name that other
+// outputs can run after it by, its own dependencies, and its options.
+func (p *Pipeline) WithOutput(name string, dep Dependency, opts Options, instances ...output.Output) {
+	p.outputs = append(p.outputs, &stage{Dependency: dep, Options: opts, name: name, instances: instances})
 }
 
 func (p *Pipeline) Run(ctx context.Context) error {
 	log.Debug("running pipeline")
+	if err := p.validate(); err != nil {
+		return err
+	}
+	var failed int64
 
 	var wg sync.WaitGroup
 	in := make(chan types.Item, p.Buffer)
@@ -150,12 +340,38 @@ func (p *Pipeline) Run(ctx context.Context) error {
 	go func(ctx context.Context, source chan types.Item) {
 		log.Trace("pipeline: executing output thread")
 		defer wg.Done()
-		p.runOutputs(ctx, source)
+		failed = p.runOutputs(ctx, source)
 	}(ctx, out)
 
 	log.Debug("pipeline: waiting for threads to finish")
 	wg.Wait()
 	log.Debug("pipeline: threads finished")
 
+	if failed > 0 {
+		return errors.Errorf("pipeline: %d items failed", failed)
+	}
 	return nil
 }
+
+// Wait blocks until any of the inputs that are Waiters has items, so that the
+// pipeline can be configured and run again.
+func (p *Pipeline) Wait(ctx context.Context) error {
+	waiters := []input.Waiter{}
+	for _, in := range p.inputs {
+		if w, ok := in.(input.Waiter); ok {
+			waiters = append(waiters, w)
+		}
+	}
+	if len(waiters) == 0 {
+		return ErrNoWaiters
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, len(waiters))
+	for _, w := range waiters {
+		go func(w input.Waiter) {
+			done <- w.Wait(ctx)
+		}(w)
+	}
+	return <-done
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package pipeline
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rbtr/pachinko/plugin/output"
+	"github.com/rbtr/pachinko/types"
+)
+
+type fakeInput []string
+
+func (f fakeInput) Init(context.Context) error { return nil }
+
+func (f fakeInput) Consume(sink chan<- types.Item) {
+	for _, p := range f {
+		sink <- types.Item{SourcePath: p}
+	}
+}
+
+// recorder records the paths and outcomes of the items it receives.
+type recorder struct {
+	sync.Mutex
+	paths    []string
+	outcomes []string
+}
+
+func (r *recorder) Init(context.Context, output.Config) error { return nil }
+
+func (r *recorder) Receive(in <-chan types.Item) {
+	for m := range in {
+		r.Lock()
+		r.paths = append(r.paths, m.SourcePath)
+		r.outcomes = append(r.outcomes, m.Outcome)
+		r.Unlock()
+	}
+}
+
+// outcome is the outcome of every item, or mixed if they differ.
+func (r *recorder) outcome() string {
+	for _, o := range r.outcomes {
+		if o != r.outcomes[0] {
+			return "mixed"
+		}
+	}
+	return r.outcomes[0]
+}
+
+func (r *recorder) sorted() string {
+	sort.Strings(r.paths)
+	return strings.Join(r.paths, ",")
+}
+
+// mover reports the items with "fail" in their path as failed.
+type mover struct {
+	recorder
+	results chan<- output.Result
+}
+
+func (m *mover) Report(results chan<- output.Result) {
+	m.results = results
+}
+
+func (m *mover) Receive(in <-chan types.Item) {
+	for i := range in {
+		outcome := output.Moved
+		if strings.Contains(i.SourcePath, "fail") {
+			outcome = output.Failed
+		}
+		m.results <- output.Result{Item: i, Outcome: outcome}
+	}
+}
+
+func TestPipeline_dependencies(t *testing.T) {
+	all, moved, failed, chained := &recorder{}, &recorder{}, &recorder{}, &recorder{}
+	p := NewPipeline()
+	p.WithInputs(fakeInput{"a", "fail-b", "c", "tv/d", "tv/fail-e"})
+	p.WithOutputs(all)
+	p.WithOutput("mover", Dependency{}, Options{Buffer: 2, Concurrency: 2}, &mover{}, &mover{})
+	p.WithOutput("tv-mover", Dependency{}, Options{}, FilterOutput(&mover{}, Filter{Sources: []string{"tv"}}))
+	p.WithOutput("", Dependency{After: []string{"mover"}}, Options{}, moved)
+	p.WithOutput("", Dependency{After: []string{"mover", "tv-mover"}, On: []string{output.Failed}}, Options{}, failed)
+	p.WithOutput("chained-mover", Dependency{After: []string{"mover"}, On: []string{output.Failed}}, Options{}, &mover{})
+	p.WithOutput("", Dependency{After: []string{"chained-mover"}, On: []string{output.Failed}}, Options{}, chained)
+
+	err := p.Run(context.TODO())
+	// fail-b and tv/fail-e fail in the mover and again in the chained mover,
+	// and are only counted once
+	if err == nil || err.Error() != "pipeline: 2 items failed" {
+		t.Errorf("got error %v", err)
+	}
+	if got := all.sorted(); got != "a,c,fail-b,tv/d,tv/fail-e" {
+		t.Errorf("got all %s", got)
+	}
+	if got := moved.sorted(); got != "a,c,tv/d" {
+		t.Errorf("got moved %s", got)
+	}
+	// the tv-mover skips everything, because none of the items have the tv source
+	if got := failed.sorted(); got != "fail-b,tv/fail-e" {
+		t.Errorf("got failed %s", got)
+	}
+	if got := chained.sorted(); got != "fail-b,tv/fail-e" {
+		t.Errorf("got chained %s", got)
+	}
+	// the outputs that run after others receive the outcomes
+	for name, tt := range map[string]struct {
+		r    *recorder
+		want string
+	}{
+		"all":     {all, ""},
+		"moved":   {moved, output.Moved},
+		"failed":  {failed, output.Failed},
+		"chained": {chained, output.Failed},
+	} {
+		if got := tt.r.outcome(); got != tt.want {
+			t.Errorf("got %s outcome %q, want %q", name, got, tt.want)
+		}
+	}
+}
+
+func TestPipeline_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs func(p *Pipeline)
+		wantErr string
+	}{
+		{
+			name: "unknown",
+			outputs: func(p *Pipeline) {
+				p.WithOutput("notify", Dependency{After: []string{"path-mover"}}, Options{}, &recorder{})
+			},
+			wantErr: "pipeline: output notify runs after path-mover, which is not configured",
+		},
+		{
+			name: "not a reporter",
+			outputs: func(p *Pipeline) {
+				p.WithOutput("logger", Dependency{}, Options{}, &recorder{})
+				p.WithOutput("notify", Dependency{After: []string{"logger"}}, Options{}, &recorder{})
+			},
+			wantErr: "pipeline: output notify runs after logger, which does not report outcomes",
+		},
+		{
+			name: "cycle",
+			outputs: func(p *Pipeline) {
+				p.WithOutput("a", Dependency{After: []string{"b"}}, Options{}, &mover{})
+				p.WithOutput("b", Dependency{After: []string{"a"}}, Options{}, &mover{})
+			},
+			wantErr: "pipeline: output a runs after itself",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPipeline()
+			tt.outputs(p)
+			if err := p.Run(context.TODO()); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("got error %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// generator is an input of n items with identifiers.
+type generator int
+
+func (g generator) Init(context.Context) error { return nil }
+
+func (g generator) Consume(sink chan<- types.Item) {
+	for i := 0; i < int(g); i++ {
+		sink <- types.Item{
+			Identifiers: map[string]string{"tvdb": strconv.Itoa(i)},
+			SourcePath:  "/src/" + strconv.Itoa(i),
+		}
+	}
+}
+
+// counter counts the items it receives, writing to their identifiers so
+// that the race detector catches items that are shared between outputs.
+type counter struct {
+	n int64
+}
+
+func (c *counter) Init(context.Context, output.Config) error { return nil }
+
+func (c *counter) Receive(in <-chan types.Item) {
+	for m := range in {
+		m.Identifiers["seen"] = "true"
+		atomic.AddInt64(&c.n, 1)
+	}
+}
+
+// sampler samples the peak number of goroutines and heap in use while the
+// pipeline runs.
+type sampler struct {
+	goroutines int
+	heap       uint64
+	stop, done chan struct{}
+}
+
+func sample() *sampler {
+	s := &sampler{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		var mem runtime.MemStats
+		for {
+			if n := runtime.NumGoroutine(); n > s.goroutines {
+				s.goroutines = n
+			}
+			runtime.ReadMemStats(&mem)
+			if mem.HeapInuse > s.heap {
+				s.heap = mem.HeapInuse
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}()
+	return s
+}
+
+func (s *sampler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// run runs a pipeline of n items with several outputs, some after a mover.
+func run(t testing.TB, n int) (*sampler, []*counter) {
+	counters := []*counter{{}, {}, {}, {}}
+	p := NewPipeline()
+	p.WithInputs(generator(n))
+	p.WithOutputs(counters[0], counters[1])
+	p.WithOutput("mover", Dependency{}, Options{Concurrency: 2}, &mover{}, &mover{})
+	p.WithOutput("", Dependency{After: []string{"mover"}}, Options{Buffer: 64}, counters[2])
+	p.WithOutput("", Dependency{After: []string{"mover"}}, Options{}, counters[3])
+	s := sample()
+	if err := p.Run(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	return s, counters
+}
+
+func TestPipeline_bounded(t *testing.T) {
+	before := runtime.NumGoroutine()
+	s, counters := run(t, 20000)
+	for i, c := range counters {
+		if c.n != 20000 {
+			t.Errorf("output %d got %d items", i, c.n)
+		}
+	}
+	// the inputs, processors, outputs, and their distributors
+	if s.goroutines-before > 30 {
+		t.Errorf("got %d goroutines", s.goroutines-before)
+	}
+}
+
+// BenchmarkPipeline_100k broadcasts 100k items, like the files of a large
+// library, to several outputs. The peak goroutines and heap stay flat as the
+// number of items grows.
+func BenchmarkPipeline_100k(b *testing.B) {
+	b.ReportAllocs()
+	goroutines, heap := 0, uint64(0)
+	for i := 0; i < b.N; i++ {
+		s, _ := run(b, 100000)
+		if s.goroutines > goroutines {
+			goroutines = s.goroutines
+		}
+		if s.heap > heap {
+			heap = s.heap
+		}
+	}
+	b.ReportMetric(float64(goroutines), "peak-goroutines")
+	b.ReportMetric(float64(heap)/(1<<20), "peak-heap-MiB")
+}
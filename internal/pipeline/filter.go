@@ -0,0 +1,140 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package pipeline
+
+import (
+	"sync"
+
+	"github.com/rbtr/pachinko/plugin/output"
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+)
+
+// Filter scopes a plugin to a subset of the items in the datastream.
+// It is configured alongside the plugin's own options.
+type Filter struct {
+	// Sources are the input source labels the plugin applies to, empty is all
+	Sources []string `mapstructure:"sources" description:"the input source labels the plugin applies to, empty is all"`
+	// When are conditions on the items the plugin applies to
+	When When `mapstructure:"when" description:"conditions on the items the plugin applies to"`
+
+	conditions []*condition
+}
+
+// Compile parses the When expressions. It must be called before the Filter
+// is used if there are any.
+func (f *Filter) Compile() error {
+	f.conditions = nil
+	for _, expr := range f.When.Match {
+		c, err := compile(expr)
+		if err != nil {
+			return err
+		}
+		f.conditions = append(f.conditions, c)
+	}
+	return nil
+}
+
+// IsEmpty is true if the Filter matches everything.
+func (f *Filter) IsEmpty() bool {
+	return len(f.Sources) == 0 && f.When.IsEmpty()
+}
+
+// Match tests if the Item is in scope of the Filter.
+func (f *Filter) Match(m types.Item) bool {
+	if len(f.Sources) > 0 && !oneOf(f.Sources, func(s string) bool { return s == m.Source }) {
+		return false
+	}
+	return f.When.match(&m, f.conditions)
+}
+
+// filteredProcessor passes items that match the Filter through the wrapped
+// Processor and routes the rest around it.
+type filteredProcessor struct {
+	processor.Processor
+	filter Filter
+}
+
+// FilterProcessor wraps the Processor so that it only receives items that
+// match the Filter.
+func FilterProcessor(p processor.Processor, f Filter) processor.Processor {
+	if f.IsEmpty() {
+		return p
+	}
+	return &filteredProcessor{p, f}
+}
+
+func (p *filteredProcessor) Process(in <-chan types.Item, out chan<- types.Item) {
+	var wg sync.WaitGroup
+	matched := make(chan types.Item)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Processor.Process(matched, out)
+	}()
+	for m := range in {
+		if p.filter.Match(m) {
+			matched <- m
+		} else {
+			out <- m
+		}
+	}
+	close(matched)
+	wg.Wait()
+}
+
+// filteredOutput only passes items that match the Filter to the wrapped
+// Output.
+type filteredOutput struct {
+	output.Output
+	filter  Filter
+	results chan<- output.Result
+}
+
+// FilterOutput wraps the Output so that it only receives items that match
+// the Filter.
+func FilterOutput(o output.Output, f Filter) output.Output {
+	if f.IsEmpty() {
+		return o
+	}
+	if _, ok := o.(output.Reporter); ok {
+		return &filteredReporter{&filteredOutput{Output: o, filter: f}}
+	}
+	return &filteredOutput{Output: o, filter: f}
+}
+
+func (o *filteredOutput) Receive(in <-chan types.Item) {
+	var wg sync.WaitGroup
+	matched := make(chan types.Item)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		o.Output.Receive(matched)
+	}()
+	for m := range in {
+		if o.filter.Match(m) {
+			matched <- m
+		} else if o.results != nil {
+			o.results <- output.Result{Item: m, Outcome: output.Skipped}
+		}
+	}
+	close(matched)
+	wg.Wait()
+}
+
+// filteredReporter is a filteredOutput that wraps a Reporter.
+type filteredReporter struct {
+	*filteredOutput
+}
+
+// Report reports the results of the wrapped Reporter and reports the items
+// that don't match the Filter as skipped.
+func (o *filteredReporter) Report(results chan<- output.Result) {
+	o.Output.(output.Reporter).Report(results)
+	o.results = results
+}
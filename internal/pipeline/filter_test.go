@@ -0,0 +1,117 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+func TestFilterProcessor(t *testing.T) {
+	p := FilterProcessor(processor.Func(func(in <-chan types.Item, out chan<- types.Item) {
+		for m := range in {
+			m.Delete = true
+			out <- m
+		}
+	}), Filter{Sources: []string{"tv"}})
+
+	in := make(chan types.Item)
+	out := make(chan types.Item)
+	go func() {
+		for _, s := range []string{"tv", "movies", "tv"} {
+			in <- types.Item{Source: s}
+		}
+		close(in)
+	}()
+	go func() {
+		p.Process(in, out)
+		close(out)
+	}()
+
+	count := 0
+	for m := range out {
+		count++
+		if m.Delete != (m.Source == "tv") {
+			t.Errorf("item from %s processed = %t", m.Source, m.Delete)
+		}
+	}
+	if count != 3 {
+		t.Errorf("got %d items, want 3", count)
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	m := types.Item{
+		Category:    types.Video,
+		Identifiers: map[string]string{"tvdb": "5141261"},
+		MediaType:   tv.TV,
+		Size:        1 << 30,
+		Source:      "tv",
+		SourcePath:  "/downloads/Mr.Robot.S01E01.1080p.mkv",
+	}
+	m.TVMetadata.Name = "Mr Robot"
+	m.TVMetadata.Season.Number = 1
+	m.TVMetadata.Episode.Number = 1
+	m.VideoMetadata.Resolution.Height = 1080
+
+	tests := []struct {
+		name string
+		f    Filter
+		want bool
+	}{
+		{"empty", Filter{}, true},
+		{"source", Filter{Sources: []string{"movies", "tv"}}, true},
+		{"other source", Filter{Sources: []string{"movies"}}, false},
+		{"category", Filter{When: When{Categories: []string{"video"}}}, true},
+		{"media type", Filter{When: When{MediaTypes: []string{"movie"}}}, false},
+		{"path", Filter{When: When{Paths: []string{"/downloads/*"}}}, true},
+		{"file name", Filter{When: When{Paths: []string{"*.mkv"}}}, true},
+		{"other path", Filter{When: When{Paths: []string{"*.mp4"}}}, false},
+		{"identifier", Filter{When: When{Identifiers: []string{"tvdb"}}}, true},
+		{"missing identifier", Filter{When: When{Identifiers: []string{"tvdb", "imdb"}}}, false},
+		{"1080p tv", Filter{When: When{
+			MediaTypes: []string{"tv"},
+			Match:      []string{"video-metadata.resolution.height >= 1080"},
+		}}, true},
+		{"720p", Filter{When: When{Match: []string{"video-metadata.resolution.height == 720"}}}, false},
+		{"embedded", Filter{When: When{Match: []string{"tv-metadata.season.number == 1", "tv-metadata.number < 2"}}}, true},
+		{"string", Filter{When: When{Match: []string{`tv-metadata.name == "Mr Robot"`}}}, true},
+		{"regexp", Filter{When: When{Match: []string{"source-path =~ (?i)robot"}}}, true},
+		{"not regexp", Filter{When: When{Match: []string{"category !~ ^video$"}}}, false},
+		{"identifier value", Filter{When: When{Match: []string{"identifiers.tvdb == 5141261"}}}, true},
+		{"missing identifier value", Filter{When: When{Match: []string{"identifiers.imdb != ''"}}}, false},
+		{"bool", Filter{When: When{Match: []string{"delete == false"}}}, true},
+		{"size", Filter{When: When{Match: []string{"size > 1000000"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.f.Compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.f.Match(m); got != tt.want {
+				t.Errorf("Match() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Compile(t *testing.T) {
+	for _, expr := range []string{
+		"resolution >= 1080",
+		"video-metadata.resolution.height",
+		"source-path =~ (",
+	} {
+		f := Filter{When: When{Match: []string{expr}}}
+		if err := f.Compile(); err == nil {
+			t.Errorf("expected error compiling %q", expr)
+		}
+	}
+}
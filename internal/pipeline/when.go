@@ -0,0 +1,246 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package pipeline
+
+import (
+	"encoding"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+)
+
+// When are conditions on the items a plugin applies to. An item must meet
+// all of them, and one of the values of each list.
+type When struct {
+	// Categories of the items, e.g. video
+	Categories []string `mapstructure:"categories" description:"categories of the items, e.g. video"`
+	// MediaTypes of the items, e.g. tv
+	MediaTypes []string `mapstructure:"media-types" description:"media types of the items, e.g. tv"`
+	// Paths are globs matched against the source path and file name
+	Paths []string `mapstructure:"paths" description:"globs matched against the source path and file name"`
+	// Identifiers the items must have, e.g. tvdb
+	Identifiers []string `mapstructure:"identifiers" description:"identifiers the items must have, e.g. tvdb"`
+	// Match are expressions on the fields of the items, like
+	// "video-metadata.resolution.height >= 1080"
+	Match []string `mapstructure:"match" description:"expressions on the fields of the items, like video-metadata.resolution.height >= 1080"`
+}
+
+// IsEmpty is true if there are no conditions.
+func (w *When) IsEmpty() bool {
+	return len(w.Categories) == 0 && len(w.MediaTypes) == 0 && len(w.Paths) == 0 &&
+		len(w.Identifiers) == 0 && len(w.Match) == 0
+}
+
+// condition is a compiled Match expression.
+type condition struct {
+	field []string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+var expression = regexp.MustCompile(`^\s*([\w.-]+)\s*(==|!=|<=|>=|=~|!~|<|>)\s*(.*?)\s*$`)
+
+var itemType = reflect.TypeOf(types.Item{})
+
+// compile parses the expression, checking that the field exists.
+func compile(expr string) (*condition, error) {
+	parts := expression.FindStringSubmatch(expr)
+	if parts == nil {
+		return nil, errors.Errorf("bad expression %q, want [field] [op] [value]", expr)
+	}
+	c := &condition{
+		field: strings.Split(parts[1], "."),
+		op:    parts[2],
+		value: strings.Trim(parts[3], `"'`),
+	}
+	if !hasField(itemType, c.field) {
+		return nil, errors.Errorf("bad expression %q, items have no field %s", expr, parts[1])
+	}
+	if c.op == "=~" || c.op == "!~" {
+		var err error
+		if c.re, err = regexp.Compile(c.value); err != nil {
+			return nil, errors.Wrapf(err, "bad expression %q", expr)
+		}
+	}
+	return c, nil
+}
+
+// jsonName is the name of the field in the item's JSON form.
+func jsonName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+// hasField tests if the type has the field, by JSON name.
+func hasField(t reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return len(path) == 1
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && jsonName(f) == "" {
+				if hasField(f.Type, path) {
+					return true
+				}
+			} else if jsonName(f) == path[0] && hasField(f.Type, path[1:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// field gets the field of the value, by JSON name.
+func field(v reflect.Value, path []string) (reflect.Value, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		f := v.MapIndex(reflect.ValueOf(path[0]))
+		return f, f.IsValid()
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && jsonName(f) == "" {
+				if found, ok := field(v.Field(i), path); ok {
+					return found, true
+				}
+			} else if jsonName(f) == path[0] {
+				return field(v.Field(i), path[1:])
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// text formats the value for string comparison.
+func text(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// compare compares the numbers or strings.
+func compare(op string, cmp int) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// match tests the item's field against the value. Fields that aren't set,
+// like missing identifiers, are empty.
+func (c *condition) match(m *types.Item) bool {
+	s := ""
+	v, ok := field(reflect.ValueOf(m).Elem(), c.field)
+	if ok {
+		s = text(v)
+	}
+	switch c.op {
+	case "=~":
+		return c.re.MatchString(s)
+	case "!~":
+		return !c.re.MatchString(s)
+	}
+	if ok {
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			want, err := strconv.ParseFloat(c.value, 64)
+			if err != nil {
+				return false
+			}
+			got, _ := strconv.ParseFloat(s, 64)
+			switch {
+			case got < want:
+				return compare(c.op, -1)
+			case got > want:
+				return compare(c.op, 1)
+			}
+			return compare(c.op, 0)
+		case reflect.Bool:
+			want, err := strconv.ParseBool(c.value)
+			if err != nil {
+				return false
+			}
+			if v.Bool() == want {
+				return compare(c.op, 0)
+			}
+			return compare(c.op, 1)
+		}
+	}
+	return compare(c.op, strings.Compare(s, c.value))
+}
+
+// glob matches the pattern against the path and its file name.
+func glob(pattern, path string) bool {
+	if ok, _ := filepath.Match(pattern, path); ok {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, filepath.Base(path))
+	return ok
+}
+
+func oneOf(list []string, f func(string) bool) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if f(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// match tests the item against the conditions.
+func (w *When) match(m *types.Item, conditions []*condition) bool {
+	if !oneOf(w.Categories, func(s string) bool { return s == string(m.Category) }) ||
+		!oneOf(w.MediaTypes, func(s string) bool { return s == string(m.MediaType) }) ||
+		!oneOf(w.Paths, func(s string) bool { return glob(s, m.SourcePath) }) {
+		return false
+	}
+	for _, id := range w.Identifiers {
+		if m.Identifiers[id] == "" {
+			return false
+		}
+	}
+	for _, c := range conditions {
+		if !c.match(m) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package plan serializes the actions that a sort would take, so that they can
+be reviewed and edited before they are applied.
+*/
+package plan
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/filesystem"
+	"github.com/rbtr/pachinko/types"
+)
+
+// Version of the plan file format.
+const Version = 1
+
+// Kind is the kind of an Action.
+type Kind string
+
+const (
+	Move   Kind = "move"
+	Delete Kind = "delete"
+)
+
+// Action is a change to make to an item.
+type Action struct {
+	Action      Kind   `json:"action"`
+	Source      string `json:"source"`
+	Destination string `json:"destination,omitempty"`
+	// Item is the item as it left the processors, for the outputs that use
+	// its metadata
+	Item types.Item `json:"item"`
+}
+
+// Plan is the list of actions.
+type Plan struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Actions []Action  `json:"actions"`
+}
+
+// New returns an empty Plan.
+func New() *Plan {
+	return &Plan{Version: Version, Created: time.Now(), Actions: []Action{}}
+}
+
+// Add adds the action for the item to the plan, if there is one.
+func (p *Plan) Add(m types.Item) {
+	switch {
+	case m.Delete:
+		p.Actions = append(p.Actions, Action{Action: Delete, Source: m.SourcePath, Item: m})
+	case m.DestinationPath != "":
+		p.Actions = append(p.Actions, Action{Action: Move, Source: m.SourcePath, Destination: m.DestinationPath, Item: m})
+	}
+}
+
+// ToItem returns the item with the action's paths, which may have been
+// edited.
+func (a *Action) ToItem() types.Item {
+	m := a.Item
+	m.SourcePath = a.Source
+	m.DestinationPath = a.Destination
+	m.Delete = a.Action == Delete
+	if m.Delete {
+		m.DestinationPath = ""
+	}
+	return m
+}
+
+// local is true if the path can be checked on the local filesystem.
+func local(path string) bool {
+	return filepath.IsAbs(path) && !filesystem.IsURL(path)
+}
+
+// Validate checks that the action can still be applied: the source exists
+// and the destination is free. Paths that aren't on the local filesystem
+// are not checked.
+func (a *Action) Validate() error {
+	switch a.Action {
+	case Move:
+		if a.Destination == "" {
+			return errors.Errorf("move of %s has no destination", a.Source)
+		}
+		if local(a.Destination) {
+			if _, err := os.Stat(a.Destination); err == nil {
+				return errors.Errorf("destination %s already exists", a.Destination)
+			}
+		}
+	case Delete:
+	default:
+		return errors.Errorf("unknown action %s for %s", a.Action, a.Source)
+	}
+	if local(a.Source) {
+		if _, err := os.Stat(a.Source); err != nil {
+			return errors.Errorf("source %s no longer exists", a.Source)
+		}
+	}
+	return nil
+}
+
+// Validate checks all of the actions, returning their errors.
+func (p *Plan) Validate() []error {
+	if p.Version != Version {
+		return []error{errors.Errorf("unsupported plan version %d", p.Version)}
+	}
+	errs := []error{}
+	dests := map[string]string{}
+	for i := range p.Actions {
+		a := &p.Actions[i]
+		if err := a.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if a.Action != Move {
+			continue
+		}
+		if src, ok := dests[a.Destination]; ok {
+			errs = append(errs, errors.Errorf("%s and %s are both moved to %s", src, a.Source, a.Destination))
+		}
+		dests[a.Destination] = a.Source
+	}
+	return errs
+}
+
+// Write writes the plan to the file.
+func (p *Plan) Write(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read reads the plan from the file.
+func Read(path string) (*Plan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p := &Plan{}
+	if err := json.NewDecoder(f).Decode(p); err != nil {
+		return nil, errors.Wrapf(err, "error reading plan %s", path)
+	}
+	return p, nil
+}
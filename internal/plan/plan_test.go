@@ -0,0 +1,76 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package plan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+func TestPlan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.mkv", "b.nfo", "taken.mkv"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	episode := types.Item{
+		FileType:        types.File,
+		Identifiers:     map[string]string{"tvdb": "1"},
+		MediaType:       tv.TV,
+		SourcePath:      filepath.Join(dir, "a.mkv"),
+		DestinationPath: filepath.Join(dir, "Show", "a.mkv"),
+	}
+	episode.TVMetadata.Name = "Show"
+	p := New()
+	p.Add(episode)
+	p.Add(types.Item{FileType: types.File, SourcePath: filepath.Join(dir, "b.nfo"), Delete: true})
+	p.Add(types.Item{FileType: types.File, SourcePath: filepath.Join(dir, "skipped.txt")})
+	if len(p.Actions) != 2 {
+		t.Fatalf("got %d actions, want 2", len(p.Actions))
+	}
+
+	path := filepath.Join(dir, "plan.json")
+	if err := p.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := read.Validate(); len(errs) > 0 {
+		t.Errorf("got errors %v", errs)
+	}
+	if got := read.Actions[0].ToItem(); !reflect.DeepEqual(got.TVMetadata, episode.TVMetadata) || got.DestinationPath != episode.DestinationPath {
+		t.Errorf("got %#v, want %#v", got, episode)
+	}
+
+	// an edited destination is used
+	read.Actions[0].Destination = filepath.Join(dir, "edited.mkv")
+	if got := read.Actions[0].ToItem(); got.DestinationPath != filepath.Join(dir, "edited.mkv") {
+		t.Errorf("got destination %s", got.DestinationPath)
+	}
+
+	// stale plans are not valid
+	read.Actions[0].Destination = filepath.Join(dir, "taken.mkv")
+	read.Actions = append(read.Actions, Action{Action: Move, Source: filepath.Join(dir, "gone.mkv"), Destination: filepath.Join(dir, "taken.mkv")})
+	if errs := read.Validate(); len(errs) != 3 {
+		t.Errorf("got errors %v, want 3", errs)
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+
+	"github.com/rbtr/pachinko/internal/plan"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// PlanInput pushes the items of the actions in a plan in to the pipeline.
+type PlanInput struct {
+	Plan *plan.Plan
+}
+
+// Init init.
+func (p *PlanInput) Init(context.Context) error {
+	return nil
+}
+
+// Consume implements the Plugin interface on the PlanInput.
+func (p *PlanInput) Consume(sink chan<- types.Item) {
+	log.Trace("started plan input")
+	for i := range p.Plan.Actions {
+		m := p.Plan.Actions[i].ToItem()
+		if m.Identifiers == nil {
+			m.Identifiers = map[string]string{}
+		}
+		log.Debugf("plan_input: %s %s", p.Plan.Actions[i].Action, m.SourcePath)
+		sink <- m
+	}
+}
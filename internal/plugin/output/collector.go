@@ -0,0 +1,35 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+
+	"github.com/rbtr/pachinko/plugin/output"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Collector is an output that keeps the items for the caller of the
+// pipeline.
+type Collector struct {
+	Items []types.Item
+}
+
+// Init init.
+func (c *Collector) Init(ctx context.Context, cfg output.Config) error {
+	return nil
+}
+
+// Receive implements the Plugin interface on the Collector.
+func (c *Collector) Receive(in <-chan types.Item) {
+	log.Trace("started collector output")
+	for m := range in {
+		c.Items = append(c.Items, m)
+	}
+}
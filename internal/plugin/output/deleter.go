@@ -11,7 +11,10 @@ import (
 	"container/heap"
 	"context"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/rbtr/pachinko/internal/filesystem"
 	"github.com/rbtr/pachinko/plugin/output"
 	"github.com/rbtr/pachinko/types"
 	log "github.com/sirupsen/logrus"
@@ -29,18 +32,38 @@ func (d *Deleter) Init(ctx context.Context, cfg output.Config) error {
 }
 
 // Receive implements the Plugin interface on the Deleter.
+//
+// When it runs after the movers, it receives the items they skipped or
+// failed once they are done, and won't delete the directories of the items
+// that are still at their source because they failed to move.
 func (d *Deleter) Receive(c <-chan types.Item) {
 	log.Trace("started deleter output")
 	h := &stringHeap{}
+	queued := map[string]bool{}
+	unmoved := []string{}
 	for m := range c {
 		log.Debugf("deleter_output: received_input %#v", m)
-		if m.Delete {
+		// remote paths are deleted by the outputs that handle them
+		if m.Delete && !filesystem.IsURL(m.SourcePath) && !queued[m.SourcePath] {
 			log.Infof("deleter_output: queueing %s", m.SourcePath)
 			heap.Push(h, m.SourcePath)
+			queued[m.SourcePath] = true
+		} else if !m.Delete && m.DestinationPath != "" {
+			unmoved = append(unmoved, m.SourcePath)
+		}
+	}
+	failed := []string{}
+	for _, p := range unmoved {
+		if _, err := os.Stat(p); err == nil {
+			failed = append(failed, p)
 		}
 	}
 	for h.Len() > 0 {
 		path := heap.Pop(h).(string)
+		if contains(path, failed) {
+			log.Warnf("deleter_output: not deleting %s, it contains items that failed to move", path)
+			continue
+		}
 		log.Infof("deleter_output: deleting %s", path)
 		if d.dryRun {
 			continue
@@ -51,6 +74,17 @@ func (d *Deleter) Receive(c <-chan types.Item) {
 	}
 }
 
+// contains tests if any of the paths are in the directory.
+func contains(dir string, paths []string) bool {
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	for _, p := range paths {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 type stringHeap []string
 
 func (h stringHeap) Len() int           { return len(h) }
@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbtr/pachinko/internal/s3"
+	"github.com/rbtr/pachinko/plugin/output"
+	"github.com/rbtr/pachinko/types"
+)
+
+// TestDeleter_s3 checks that the items of an s3 input that reach the deleter,
+// because the s3-mover failed to delete them, aren't deleted from the working
+// directory by their keys.
+func TestDeleter_s3(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deleter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd) // nolint: errcheck
+
+	key := filepath.Join("tv", "show.nfo")
+	local := filepath.Join(dir, "local.nfo")
+	for _, p := range []string{key, local} {
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := &Deleter{}
+	if err := d.Init(context.TODO(), output.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	c := make(chan types.Item, 2)
+	c <- types.Item{SourcePath: s3.URL("media", "tv/show.nfo"), Delete: true}
+	c <- types.Item{SourcePath: local, Delete: true}
+	close(c)
+	d.Receive(c)
+
+	if _, err := os.Stat(key); err != nil {
+		t.Errorf("the s3 item's key was deleted locally: %s", err)
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Errorf("the local item wasn't deleted: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+
+	"github.com/rbtr/pachinko/internal/plan"
+	"github.com/rbtr/pachinko/plugin/output"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Planner is an output that adds the actions for the items to a plan
+// instead of taking them.
+type Planner struct {
+	Plan *plan.Plan
+}
+
+// Init init.
+func (p *Planner) Init(ctx context.Context, cfg output.Config) error {
+	return nil
+}
+
+// Receive implements the Plugin interface on the Planner.
+func (p *Planner) Receive(c <-chan types.Item) {
+	log.Trace("started planner output")
+	for m := range c {
+		log.Debugf("planner_output: received_input %#v", m)
+		p.Plan.Add(m)
+	}
+}
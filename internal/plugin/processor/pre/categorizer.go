@@ -13,11 +13,25 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/media"
 	"github.com/rbtr/pachinko/plugin/processor"
 	"github.com/rbtr/pachinko/types"
 	log "github.com/sirupsen/logrus"
 )
 
+// SniffMode controls when file contents are used to categorize files.
+type SniffMode string
+
+const (
+	// SniffOff categorizes by file extension only.
+	SniffOff SniffMode = "off"
+	// SniffFallback categorizes by content when the extension is unknown.
+	SniffFallback SniffMode = "fallback"
+	// SniffOverride categorizes by content when it is recognized and by
+	// extension otherwise.
+	SniffOverride SniffMode = "override"
+)
+
 var defaultCategoryFileExtensions = map[types.Category][]string{
 	types.Archive:  types.ArchiveExtensions,
 	types.Image:    types.ImageExtensions,
@@ -27,16 +41,23 @@ var defaultCategoryFileExtensions = map[types.Category][]string{
 }
 
 type FileCategorizer struct {
-	CategoryFileExtensions  map[types.Category][]string `mapstructure:"file-extensions"`
+	CategoryFileExtensions  map[types.Category][]string `mapstructure:"file-extensions" description:"the file extensions of each category"`
+	Sniff                   SniffMode                   `mapstructure:"sniff" description:"whether the contents of files are sniffed to categorize them: off, as a fallback for unknown extensions, or to override the extension" enum:"off,fallback,override"`
 	fileExtensionCategories map[string]types.Category
 }
 
 func (cat *FileCategorizer) Init(context.Context) error {
 	log.Trace("categorizer initializing")
+	switch cat.Sniff {
+	case SniffOff, SniffFallback, SniffOverride:
+	default:
+		return errors.Errorf("unknown sniff mode %s, must be one of (%s,%s,%s)", cat.Sniff, SniffOff, SniffFallback, SniffOverride)
+	}
 	cat.fileExtensionCategories = map[string]types.Category{}
 	// transpose the category/extension map for immediate lookups
 	for k, v := range cat.CategoryFileExtensions {
 		for _, vv := range v {
+			vv = strings.ToLower(strings.Trim(vv, "."))
 			if kk, ok := cat.fileExtensionCategories[vv]; ok {
 				return errors.Errorf("duplicate filetype::category mapping: %s::%s already exists as %s::%s, ", vv, k, vv, kk)
 			}
@@ -47,24 +68,61 @@ func (cat *FileCategorizer) Init(context.Context) error {
 	return nil
 }
 
-func (cat *FileCategorizer) identify(m types.Item) types.Item {
-	// don't attempt to categorize directories
-	if m.FileType == types.Directory {
-		return m
-	}
-
-	ext := path.Ext(m.SourcePath)
-
-	category := types.Unknown
+// byExtension looks up the Category of the path by its file extension,
+// ignoring case.
+func (cat *FileCategorizer) byExtension(p string) types.Category {
+	ext := path.Ext(p)
 	if ext == "" {
 		log.Debug("categorizer: no extension, unknown category")
+		return types.Unknown
 	}
-
-	trimmed := strings.Trim(ext, ".")
+	trimmed := strings.ToLower(strings.Trim(ext, "."))
 	log.Tracef("categorizer: lookup extension '%s'", trimmed)
 	if c, ok := cat.fileExtensionCategories[trimmed]; ok {
 		log.Debugf("categorizer: identified %s as %s", ext, c)
-		category = c
+		return c
+	}
+	return types.Unknown
+}
+
+// byContent identifies the Category of the file from its magic bytes.
+func (cat *FileCategorizer) byContent(p string) types.Category {
+	c, err := media.Sniff(p)
+	if err != nil {
+		log.Debugf("categorizer: can't sniff %s: %s", p, err)
+		return types.Unknown
+	}
+	if c != types.Unknown {
+		log.Debugf("categorizer: identified %s content as %s", p, c)
+	}
+	return c
+}
+
+// Categorize sets the Category of the Item from its file extension and,
+// depending on the sniff mode, its contents. Items that already have a
+// Category are left alone.
+func (cat *FileCategorizer) Categorize(m types.Item) types.Item {
+	// don't attempt to categorize directories
+	if m.FileType == types.Directory {
+		return m
+	}
+	// keep categories supplied by the input
+	if m.Category != types.Unknown {
+		return m
+	}
+
+	category := types.Unknown
+	switch cat.Sniff {
+	case SniffOverride:
+		if category = cat.byContent(m.SourcePath); category == types.Unknown {
+			category = cat.byExtension(m.SourcePath)
+		}
+	case SniffFallback:
+		if category = cat.byExtension(m.SourcePath); category == types.Unknown {
+			category = cat.byContent(m.SourcePath)
+		}
+	default:
+		category = cat.byExtension(m.SourcePath)
 	}
 	m.Category = category
 	return m
@@ -74,7 +132,7 @@ func (cat *FileCategorizer) Process(in <-chan types.Item, out chan<- types.Item)
 	log.Trace("started categorizer")
 	for m := range in {
 		log.Debugf("categorizer: received input: %v", m)
-		out <- cat.identify(m)
+		out <- cat.Categorize(m)
 	}
 }
 
@@ -83,7 +141,12 @@ func (*FileCategorizer) Type() processor.Type {
 }
 
 func NewCategorizer() *FileCategorizer {
+	extensions := map[types.Category][]string{}
+	for k, v := range defaultCategoryFileExtensions {
+		extensions[k] = append([]string{}, v...)
+	}
 	return &FileCategorizer{
-		CategoryFileExtensions: defaultCategoryFileExtensions,
+		CategoryFileExtensions: extensions,
+		Sniff:                  SniffFallback,
 	}
 }
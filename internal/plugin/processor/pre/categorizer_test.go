@@ -0,0 +1,93 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package pre
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+func TestFileCategorizer_Categorize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "categorizer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mkv := []byte{0x1A, 0x45, 0xDF, 0xA3}
+	files := map[string][]byte{
+		"upper.MKV":     nil,
+		"noext":         mkv,
+		"wrong.txt":     mkv,
+		"unknown.xyz":   nil,
+		"subtitles.srt": []byte("1\n"),
+	}
+	for name, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sniff SniffMode
+		want  map[string]types.Category
+	}{
+		{SniffOff, map[string]types.Category{
+			"upper.MKV":     types.Video,
+			"noext":         types.Unknown,
+			"wrong.txt":     types.Text,
+			"unknown.xyz":   types.Unknown,
+			"subtitles.srt": types.Subtitle,
+		}},
+		{SniffFallback, map[string]types.Category{
+			"upper.MKV":     types.Video,
+			"noext":         types.Video,
+			"wrong.txt":     types.Text,
+			"unknown.xyz":   types.Unknown,
+			"subtitles.srt": types.Subtitle,
+		}},
+		{SniffOverride, map[string]types.Category{
+			"upper.MKV":     types.Video,
+			"noext":         types.Video,
+			"wrong.txt":     types.Video,
+			"unknown.xyz":   types.Unknown,
+			"subtitles.srt": types.Subtitle,
+		}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(string(tt.sniff), func(t *testing.T) {
+			cat := NewCategorizer()
+			cat.Sniff = tt.sniff
+			if err := cat.Init(context.TODO()); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				m := cat.Categorize(types.Item{FileType: types.File, SourcePath: filepath.Join(dir, name)})
+				if m.Category != want {
+					t.Errorf("%s: got %q, want %q", name, m.Category, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileCategorizer_Init(t *testing.T) {
+	cat := NewCategorizer()
+	cat.CategoryFileExtensions[types.Text] = append(cat.CategoryFileExtensions[types.Text], "MKV")
+	if err := cat.Init(context.TODO()); err == nil {
+		t.Error("expected duplicate extension error")
+	}
+	if len(defaultCategoryFileExtensions[types.Text]) != len(types.TextExtensions) {
+		t.Error("defaults were modified")
+	}
+}
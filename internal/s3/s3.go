@@ -0,0 +1,458 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package s3 is a minimal client for S3-compatible object storage,
+supporting only the operations needed to organize a bucket: listing,
+server-side copying, and deleting objects.
+*/
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// emptyHash is the hex sha256 of an empty payload.
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// maxCopySize is the largest object that can be copied in a single request,
+// larger objects are copied in parts of copyPartSize, which fits the largest
+// object S3 allows in its limit of 10000 parts.
+var (
+	maxCopySize  int64 = 5 << 30
+	copyPartSize int64 = 1 << 30
+)
+
+// Config is the connection config for an S3-compatible endpoint.
+type Config struct {
+	// Endpoint the base url of the service, like https://s3.amazonaws.com
+	Endpoint string `mapstructure:"endpoint" description:"the base url of the service, like https://s3.amazonaws.com"`
+	// Region the bucket is in, used for signing
+	Region string `mapstructure:"region" description:"the region the bucket is in, used for signing"`
+	// Bucket to operate on
+	Bucket string `mapstructure:"bucket" description:"the bucket to operate on"`
+	// AccessKey id
+	AccessKey string `mapstructure:"access-key" description:"the access key id"`
+	// SecretKey for the AccessKey
+	SecretKey string `mapstructure:"secret-key" description:"the secret key of the access key"`
+	// PathStyle addressing (endpoint/bucket/key) instead of virtual-hosted
+	// style (bucket.endpoint/key), required by most self-hosted services
+	PathStyle bool `mapstructure:"path-style" description:"use path style addressing (endpoint/bucket/key) instead of virtual-hosted style (bucket.endpoint/key), required by most self-hosted services"`
+}
+
+// Object is an object listed from a bucket.
+type Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+}
+
+// Client is an S3 client.
+type Client struct {
+	Config
+	HTTPClient *http.Client
+
+	endpoint *url.URL
+	now      func() time.Time
+}
+
+// NewClient validates the Config and returns a Client.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("s3: endpoint must be set")
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3: bucket must be set")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3: bad endpoint")
+	}
+	return &Client{
+		Config:     cfg,
+		HTTPClient: http.DefaultClient,
+		endpoint:   u,
+		now:        time.Now,
+	}, nil
+}
+
+// URL is the s3://bucket/key url of the key in the bucket. Objects are
+// represented in the datastream by their urls, so that their keys can't be
+// confused with local paths.
+func URL(bucket, key string) string {
+	return "s3://" + bucket + "/" + key
+}
+
+// Key is the key of the url of an object, and false if the url isn't of an
+// object in the bucket.
+func Key(bucket, url string) (string, bool) {
+	prefix := URL(bucket, "")
+	if !strings.HasPrefix(url, prefix) || len(url) == len(prefix) {
+		return "", false
+	}
+	return url[len(prefix):], true
+}
+
+// objectURL returns the url of the key in the bucket.
+func (c *Client) objectURL(bucket, key string) *url.URL {
+	u := *c.endpoint
+	if c.PathStyle {
+		u.Path = "/" + bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return &u
+}
+
+// escape URI encodes everything but the unreserved characters and the
+// separators in the path, as S3 expects for signing.
+func escape(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+// Sign adds an AWS signature version 4 Authorization header to the request.
+// The payload is signed by the hash in the X-Amz-Content-Sha256 header, which
+// is set to the hash of an empty payload if it isn't set.
+func Sign(req *http.Request, accessKey, secretKey, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = emptyHash
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := []string{"host"}
+	canonicalHeaders := "host:" + req.URL.Host + "\n"
+	names := []string{}
+	for k := range req.Header {
+		if lower := strings.ToLower(k); strings.HasPrefix(lower, "x-amz-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		headers = append(headers, k)
+		canonicalHeaders += k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n"
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	query := req.URL.Query()
+	keys := []string{}
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := []string{}
+	for _, k := range keys {
+		for _, v := range query[k] {
+			params = append(params, url.QueryEscape(k)+"="+strings.Replace(url.QueryEscape(v), "+", "%20", -1))
+		}
+	}
+	// normalize the sent query to the signed form
+	req.URL.RawQuery = strings.Join(params, "&")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// send signs and sends the request, with the body if it isn't nil.
+func (c *Client) send(ctx context.Context, method string, u *url.URL, header http.Header, body []byte) (*http.Response, error) {
+	u.RawPath = escape(u.Path)
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("X-Amz-Content-Sha256", sha256Hex(string(body)))
+	}
+	Sign(req, c.AccessKey, c.SecretKey, c.Region, c.now())
+	return c.HTTPClient.Do(req)
+}
+
+// do sends the request, returning an error for non-2xx responses.
+func (c *Client) do(ctx context.Context, method string, u *url.URL, header http.Header, body []byte) (*http.Response, error) {
+	res, err := c.send(ctx, method, u, header, body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, errors.Errorf("s3: %s %s: %s: %s", method, u.Path, res.Status, b)
+	}
+	return res, nil
+}
+
+type listBucketResult struct {
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+	Contents              []Object `xml:"Contents"`
+}
+
+// List calls f for every object in the bucket under the prefix.
+func (c *Client) List(ctx context.Context, prefix string, f func(Object) error) error {
+	token := ""
+	for {
+		u := c.objectURL(c.Bucket, "")
+		q := url.Values{}
+		q.Set("list-type", "2")
+		if prefix != "" {
+			q.Set("prefix", prefix)
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+		res, err := c.do(ctx, http.MethodGet, u, nil, nil)
+		if err != nil {
+			return err
+		}
+		result := listBucketResult{}
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return errors.Wrap(err, "s3: error decoding list result")
+		}
+		for _, o := range result.Contents {
+			o.ETag = strings.Trim(o.ETag, `"`)
+			if err := f(o); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Exists tests if the key exists in the bucket.
+func (c *Client) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	res, err := c.send(ctx, http.MethodHead, c.objectURL(bucket, key), nil, nil)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return false, nil
+	case res.StatusCode >= 200 && res.StatusCode <= 299:
+		return true, nil
+	}
+	return false, errors.Errorf("s3: HEAD %s: %s", key, res.Status)
+}
+
+// Copy copies the key, of the size, to the destination bucket and key on the
+// server side. Objects larger than can be copied in a single request are
+// copied in parts.
+func (c *Client) Copy(ctx context.Context, key string, size int64, destBucket, destKey string) error {
+	if size > maxCopySize {
+		return c.copyParts(ctx, key, size, destBucket, destKey)
+	}
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", escape("/"+c.Bucket+"/"+key))
+	res, err := c.do(ctx, http.MethodPut, c.objectURL(destBucket, destKey), header, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return copyError(key, res.Body)
+}
+
+// copyError returns the error in the body of a copy. Copies can fail after
+// the 200 status has been sent, with the error in the body instead of the
+// result.
+func copyError(key string, body io.Reader) error {
+	var result struct {
+		XMLName xml.Name
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return errors.Errorf("s3: copy %s: %s", key, result.Message)
+	}
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// copyParts copies the key to the destination in a multipart upload of
+// copyPartSize parts, and aborts the upload if any of the parts fail.
+func (c *Client) copyParts(ctx context.Context, key string, size int64, destBucket, destKey string) error {
+	u := c.objectURL(destBucket, destKey)
+	u.RawQuery = "uploads="
+	res, err := c.do(ctx, http.MethodPost, u, nil, nil)
+	if err != nil {
+		return err
+	}
+	var upload struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(res.Body).Decode(&upload)
+	res.Body.Close()
+	if err != nil {
+		return errors.Wrapf(err, "s3: error decoding upload of %s", key)
+	}
+	parts, err := c.copyPartsOf(ctx, key, size, destBucket, destKey, upload.UploadID)
+	if err == nil {
+		err = c.completeUpload(ctx, key, destBucket, destKey, upload.UploadID, parts)
+	}
+	if err != nil {
+		u := c.objectURL(destBucket, destKey)
+		u.RawQuery = url.Values{"uploadId": {upload.UploadID}}.Encode()
+		res, abortErr := c.do(ctx, http.MethodDelete, u, nil, nil)
+		if abortErr != nil {
+			return errors.Wrapf(err, "s3: error aborting upload (%s)", abortErr)
+		}
+		res.Body.Close()
+		return err
+	}
+	return nil
+}
+
+// copyPartsOf copies each of the parts of the key in to the upload.
+func (c *Client) copyPartsOf(ctx context.Context, key string, size int64, destBucket, destKey, uploadID string) ([]completedPart, error) {
+	parts := []completedPart{}
+	for start, n := int64(0), 1; start < size; start, n = start+copyPartSize, n+1 {
+		end := start + copyPartSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		u := c.objectURL(destBucket, destKey)
+		u.RawQuery = url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {uploadID}}.Encode()
+		header := http.Header{}
+		header.Set("X-Amz-Copy-Source", escape("/"+c.Bucket+"/"+key))
+		header.Set("X-Amz-Copy-Source-Range", fmt.Sprintf("bytes=%d-%d", start, end))
+		res, err := c.do(ctx, http.MethodPut, u, header, nil)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "s3: error reading part %d of %s", n, key)
+		}
+		if err := copyError(key, bytes.NewReader(b)); err != nil {
+			return nil, err
+		}
+		var result struct {
+			ETag string `xml:"ETag"`
+		}
+		if err := xml.Unmarshal(b, &result); err != nil {
+			return nil, errors.Wrapf(err, "s3: error decoding part %d of %s", n, key)
+		}
+		parts = append(parts, completedPart{PartNumber: n, ETag: result.ETag})
+	}
+	return parts, nil
+}
+
+// completeUpload completes the upload with the parts.
+func (c *Client) completeUpload(ctx context.Context, key, destBucket, destKey, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	u := c.objectURL(destBucket, destKey)
+	u.RawQuery = url.Values{"uploadId": {uploadID}}.Encode()
+	res, err := c.do(ctx, http.MethodPost, u, nil, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return copyError(key, res.Body)
+}
+
+// Delete deletes the key from the bucket.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	res, err := c.do(ctx, http.MethodDelete, c.objectURL(c.Bucket, key), nil, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
@@ -0,0 +1,263 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fake is an in-process, path-style, single bucket S3 server that verifies
+// request signatures.
+type fake struct {
+	sync.Mutex
+	t       *testing.T
+	bucket  string
+	objects map[string]string
+	parts   map[int]string
+	page    int
+}
+
+func (f *fake) verify(r *http.Request) bool {
+	date, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	u := *r.URL
+	u.Host = r.Host
+	req := &http.Request{Method: r.Method, URL: &u, Header: http.Header{}}
+	for k, v := range r.Header {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-") {
+			req.Header[k] = v
+		}
+	}
+	Sign(req, "key", "secret", "us-east-1", date)
+	return req.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (f *fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if !f.verify(r) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	path, _ := url.PathUnescape(r.URL.EscapedPath())
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if parts[0] != f.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := ""
+	if len(parts) > 1 {
+		key = parts[1]
+	}
+	switch r.Method {
+	case http.MethodGet:
+		keys := []string{}
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		res := listBucketResult{}
+		// one object per page to exercise pagination
+		start := 0
+		if token := r.URL.Query().Get("continuation-token"); token != "" {
+			fmt.Sscanf(token, "%d", &start)
+		}
+		if start < len(keys) {
+			res.Contents = []Object{{Key: keys[start], ETag: `"etag"`, Size: int64(len(f.objects[keys[start]]))}}
+		}
+		if start+1 < len(keys) {
+			res.IsTruncated = true
+			res.NextContinuationToken = fmt.Sprintf("%d", start+1)
+		}
+		_ = xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"ListBucketResult"`
+			listBucketResult
+		}{listBucketResult: res})
+	case http.MethodHead:
+		if _, ok := f.objects[key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case http.MethodPost:
+		if _, ok := r.URL.Query()["uploads"]; ok {
+			f.parts = map[int]string{}
+			fmt.Fprint(w, "<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>")
+			return
+		}
+		var complete struct {
+			Parts []completedPart `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body := ""
+		for _, p := range complete.Parts {
+			body += f.parts[p.PartNumber]
+		}
+		f.objects[key] = body
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case http.MethodPut:
+		src, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		body, ok := f.objects[strings.TrimPrefix(src, "/"+f.bucket+"/")]
+		if !ok {
+			fmt.Fprint(w, "<Error><Message>NoSuchKey</Message></Error>")
+			return
+		}
+		if part := r.URL.Query().Get("partNumber"); part != "" {
+			var n, start, end int
+			fmt.Sscanf(part, "%d", &n)
+			fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &start, &end)
+			f.parts[n] = body[start : end+1]
+			fmt.Fprintf(w, `<CopyPartResult><ETag>"%d"</ETag></CopyPartResult>`, n)
+			return
+		}
+		f.objects[key] = body
+		fmt.Fprint(w, "<CopyObjectResult></CopyObjectResult>")
+	case http.MethodDelete:
+		if r.URL.Query().Get("uploadId") != "" {
+			f.parts = nil
+		} else {
+			delete(f.objects, key)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newFake(t *testing.T) (*fake, *Client, func()) {
+	f := &fake{t: t, bucket: "media", objects: map[string]string{
+		"downloads/Mr Robot (2015) S01E01.mkv": "a",
+		"downloads/b+c.mkv":                    "b",
+		"other/c.mkv":                          "c",
+	}}
+	srv := httptest.NewServer(f)
+	c, err := NewClient(Config{
+		Endpoint:  srv.URL,
+		Bucket:    "media",
+		AccessKey: "key",
+		SecretKey: "secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, c, srv.Close
+}
+
+func TestClient_List(t *testing.T) {
+	_, c, done := newFake(t)
+	defer done()
+	keys := []string{}
+	if err := c.List(context.TODO(), "downloads/", func(o Object) error {
+		if o.ETag != "etag" {
+			t.Errorf("etag %s was not unquoted", o.ETag)
+		}
+		keys = append(keys, o.Key)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "downloads/Mr Robot (2015) S01E01.mkv" || keys[1] != "downloads/b+c.mkv" {
+		t.Errorf("got %v", keys)
+	}
+}
+
+func TestClient_CopyDelete(t *testing.T) {
+	f, c, done := newFake(t)
+	defer done()
+	ctx := context.TODO()
+	src := "downloads/Mr Robot (2015) S01E01.mkv"
+	dest := "tv/Mr Robot/Season 01/Mr Robot S01E01.mkv"
+	if err := c.Copy(ctx, src, 1, "media", dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := c.Exists(ctx, "media", dest); err != nil || !exists {
+		t.Errorf("dest exists = %t, %v", exists, err)
+	}
+	if exists, err := c.Exists(ctx, "media", src); err != nil || exists {
+		t.Errorf("src exists = %t, %v", exists, err)
+	}
+	if f.objects[dest] != "a" {
+		t.Errorf("got %v", f.objects)
+	}
+	if err := c.Copy(ctx, "missing", 1, "media", "dest"); err == nil {
+		t.Error("expected error copying missing key")
+	}
+}
+
+func TestClient_copyParts(t *testing.T) {
+	defer func(max, part int64) {
+		maxCopySize, copyPartSize = max, part
+	}(maxCopySize, copyPartSize)
+	maxCopySize, copyPartSize = 4, 3
+
+	f, c, done := newFake(t)
+	defer done()
+	ctx := context.TODO()
+	f.objects["downloads/large.mkv"] = "abcdefgh"
+	if err := c.Copy(ctx, "downloads/large.mkv", 8, "media", "movies/large.mkv"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.objects["movies/large.mkv"]; got != "abcdefgh" {
+		t.Errorf("got %q copied in %v", got, f.parts)
+	}
+	if err := c.Copy(ctx, "missing", 8, "media", "dest"); err == nil {
+		t.Error("expected error copying missing key")
+	}
+	if f.parts != nil {
+		t.Errorf("upload of missing key was not aborted: %v", f.parts)
+	}
+	if _, ok := f.objects["dest"]; ok {
+		t.Error("missing key was copied")
+	}
+}
+
+func TestClient_badSignature(t *testing.T) {
+	_, c, done := newFake(t)
+	defer done()
+	c.SecretKey = "wrong"
+	if err := c.Delete(context.TODO(), "other/c.mkv"); err == nil {
+		t.Error("expected forbidden error")
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		url  string
+		key  string
+		isIn bool
+	}{
+		{URL("media", "tv/show.nfo"), "tv/show.nfo", true},
+		{"s3://other/tv/show.nfo", "", false},
+		{"s3://media/", "", false},
+		{"tv/show.nfo", "", false},
+		{"/media/tv/show.nfo", "", false},
+	}
+	for _, tt := range tests {
+		key, ok := Key("media", tt.url)
+		if key != tt.key || ok != tt.isIn {
+			t.Errorf("%s: got %q, %t, want %q, %t", tt.url, key, ok, tt.key, tt.isIn)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package schema describes the options of plugins as JSON Schema.
+
+The options are the fields of the plugin with mapstructure tags, and are
+documented with description and enum tags on the fields:
+
+	type Plugin struct {
+		// Mode is item or run
+		Mode string `mapstructure:"mode" description:"run the command for each item, or once for the run" enum:"item,run"`
+	}
+
+An empty value in the enum, like enum:",json,csv", allows the option to be
+empty, for options that are defaulted when they are. The defaults are the
+values of the fields of the plugin that the registry creates.
+*/
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema draft that the schemas are written in.
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON Schema, limited to what is needed to describe configs.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Const                string             `json:"const,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// durationPattern matches the durations that time.ParseDuration parses.
+const durationPattern = `^-?([0-9]*\.?[0-9]+(ns|us|µs|ms|s|m|h))+$|^0$`
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Of is the schema of the options of the plugin, with the values of its
+// fields as the defaults. Unknown options are not allowed, as they aren't
+// when the config is decoded.
+func Of(plugin interface{}) *Schema {
+	return of(reflect.ValueOf(plugin))
+}
+
+// Merge adds the properties of the other schemas to the schema.
+func (s *Schema) Merge(others ...*Schema) *Schema {
+	if s.Properties == nil {
+		s.Properties = map[string]*Schema{}
+	}
+	for _, o := range others {
+		for k, p := range o.Properties {
+			s.Properties[k] = p
+		}
+	}
+	return s
+}
+
+// Match is the one of the schema's oneOf schemas whose const properties the
+// value has, or the schema if it has none. It is nil if none match.
+func (s *Schema) Match(value map[string]interface{}) *Schema {
+	if s == nil || len(s.OneOf) == 0 {
+		return s
+	}
+	for _, o := range s.OneOf {
+		match := true
+		for k, p := range o.Properties {
+			if p.Const != "" && value[k] != p.Const {
+				match = false
+			}
+		}
+		if match {
+			return o
+		}
+	}
+	return nil
+}
+
+func of(v reflect.Value) *Schema {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ofType(v.Type())
+		}
+		v = v.Elem()
+	}
+	s := &Schema{}
+	t := v.Type()
+	switch {
+	case t == durationType:
+		s.Type = "string"
+		s.Pattern = durationPattern
+	case t.Kind() == reflect.Struct:
+		s.Type = "object"
+		s.Properties = map[string]*Schema{}
+		s.AdditionalProperties = false
+		fields(v, s)
+	case t.Kind() == reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = ofType(t.Elem())
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s.Type = "array"
+		s.Items = ofType(t.Elem())
+	case t.Kind() == reflect.Bool:
+		s.Type = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s.Type = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s.Type = "number"
+	case t.Kind() == reflect.String:
+		s.Type = "string"
+	}
+	s.Default = value(v)
+	return s
+}
+
+// ofType is the schema of the type, without defaults.
+func ofType(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Interface {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return of(reflect.Zero(t))
+}
+
+// fields adds the fields of the struct to the properties of the schema,
+// including the fields of squashed structs.
+func fields(v reflect.Value, s *Schema) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("mapstructure"), ",")
+		squash := false
+		for _, opt := range tag[1:] {
+			squash = squash || opt == "squash"
+		}
+		name := tag[0]
+		switch {
+		case squash:
+			fields(v.Field(i), s)
+			continue
+		case field.PkgPath != "" || name == "-":
+			// unexported
+			continue
+		case name == "":
+			name = field.Name
+		}
+		p := of(v.Field(i))
+		p.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			if p.Items != nil {
+				p.Items.Enum = strings.Split(enum, ",")
+			} else {
+				p.Enum = strings.Split(enum, ",")
+			}
+		}
+		s.Properties[name] = p
+	}
+}
+
+// value is the value as a default, or nil if it is zero. Durations are
+// strings, like they are written in configs.
+func value(v reflect.Value) interface{} {
+	if !v.IsValid() || v.IsZero() {
+		return nil
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return nil
+		}
+		out := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, value(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.Len() == 0 {
+			return nil
+		}
+		out := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = value(iter.Value())
+		}
+		return out
+	case reflect.Interface, reflect.Ptr:
+		return value(v.Elem())
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package schema
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type common struct {
+	Label string `mapstructure:"label" description:"label of the items"`
+}
+
+type plugin struct {
+	common  `mapstructure:",squash"`
+	Mode    string            `mapstructure:"mode" description:"the mode" enum:"item,run"`
+	Formats []string          `mapstructure:"formats" enum:"gz,zip"`
+	Timeout time.Duration     `mapstructure:"timeout"`
+	Retries int               `mapstructure:"retries"`
+	Env     map[string]string `mapstructure:"env"`
+
+	ctx interface{}
+}
+
+func TestOf(t *testing.T) {
+	s := Of(&plugin{Mode: "item", Timeout: time.Minute})
+	if s.Type != "object" || s.AdditionalProperties != false {
+		t.Errorf("got %s with additional properties %v, want a closed object", s.Type, s.AdditionalProperties)
+	}
+	keys := []string{}
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	if len(keys) != 6 {
+		t.Errorf("got properties %v, want the 6 options", keys)
+	}
+	tests := []struct {
+		key  string
+		want Schema
+	}{
+		{"label", Schema{Type: "string", Description: "label of the items"}},
+		{"mode", Schema{Type: "string", Description: "the mode", Enum: []string{"item", "run"}, Default: "item"}},
+		{"timeout", Schema{Type: "string", Pattern: durationPattern, Default: "1m0s"}},
+		{"retries", Schema{Type: "integer"}},
+	}
+	for _, tt := range tests {
+		if got := s.Properties[tt.key]; !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.key, *got, tt.want)
+		}
+	}
+	if got := s.Properties["formats"]; got.Type != "array" || !reflect.DeepEqual(got.Items.Enum, []string{"gz", "zip"}) {
+		t.Errorf("formats: got %+v", got)
+	}
+	if got := s.Properties["env"].AdditionalProperties.(*Schema); got.Type != "string" {
+		t.Errorf("env: got values %+v", got)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	item := Of(&plugin{})
+	item.Properties["name"] = &Schema{Type: "string", Const: "p", Description: "the plugin"}
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"plugins": {Type: "array", Items: &Schema{OneOf: []*Schema{item}}},
+		},
+	}
+	b, err := s.YAML(map[string]interface{}{
+		"plugins": []interface{}{
+			map[string]interface{}{"mode": "run", "name": "p", "formats": []string{"gz"}},
+			map[string]interface{}{"mode": "run", "name": "unknown"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `plugins:
+- # the plugin
+  name: p
+  # one of: gz, zip
+  formats:
+  - gz
+  # the mode
+  # one of: item, run
+  mode: run
+- mode: run
+  name: unknown
+`
+	if got := string(b); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML encodes the config as yaml, with the description of each option and
+// the values it can be as a comment above it.
+func (s *Schema) YAML(config interface{}) ([]byte, error) {
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	for _, n := range doc.Content {
+		annotate(n, s)
+	}
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// comment is the comment for an option with the schema.
+func comment(s *Schema) string {
+	lines := []string{}
+	if s.Description != "" {
+		lines = append(lines, s.Description)
+	}
+	enum := s.Enum
+	if s.Items != nil {
+		enum = s.Items.Enum
+	}
+	values := []string{}
+	for _, v := range enum {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) > 0 {
+		lines = append(lines, fmt.Sprintf("one of: %s", strings.Join(values, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// annotate comments the keys of the node with the schema, and moves the name
+// of plugins to the top of their options.
+func annotate(n *yaml.Node, s *Schema) {
+	if s == nil {
+		return
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			p, ok := s.Properties[key.Value]
+			if !ok {
+				if additional, ok := s.AdditionalProperties.(*Schema); ok {
+					annotate(val, additional)
+				}
+				continue
+			}
+			key.HeadComment = comment(p)
+			annotate(val, p)
+			if key.Value == "name" && i > 0 {
+				content := append([]*yaml.Node{key, val}, n.Content[:i]...)
+				n.Content = append(content, n.Content[i+2:]...)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			annotate(item, s.Items.Match(values(item)))
+		}
+	}
+}
+
+// values are the scalar values of a mapping node, to match it to a schema.
+func values(n *yaml.Node) map[string]interface{} {
+	m := map[string]interface{}{}
+	if n.Kind != yaml.MappingNode {
+		return m
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i+1].Kind == yaml.ScalarNode {
+			m[n.Content[i].Value] = n.Content[i+1].Value
+		}
+	}
+	return m
+}
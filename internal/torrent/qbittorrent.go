@@ -0,0 +1,177 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package torrent
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// qbittorrent is a client for the qBittorrent Web API v2.
+type qbittorrent struct {
+	Config
+	http *http.Client
+
+	sync.Mutex
+	loggedIn bool
+}
+
+func newQBittorrent(cfg Config, client *http.Client) (*qbittorrent, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	c := *client
+	c.Jar = jar
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	return &qbittorrent{Config: cfg, http: &c}, nil
+}
+
+// login gets a session cookie, once.
+func (q *qbittorrent) login(ctx context.Context) error {
+	q.Lock()
+	defer q.Unlock()
+	if q.loggedIn {
+		return nil
+	}
+	body, err := q.do(ctx, "/api/v2/auth/login", url.Values{
+		"username": {q.User},
+		"password": {q.Password},
+	})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(body)) != "Ok." {
+		return errors.New("qbittorrent: login failed")
+	}
+	q.loggedIn = true
+	return nil
+}
+
+// do posts the form to the API method and returns the response body.
+func (q *qbittorrent) do(ctx context.Context, method string, form url.Values) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, q.URL+method, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// the api rejects requests without a matching referer when CSRF
+	// protection is enabled
+	req.Header.Set("Referer", q.URL)
+	res, err := q.http.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "qbittorrent")
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "qbittorrent")
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("qbittorrent: %s %s: %s", method, res.Status, body)
+	}
+	return body, nil
+}
+
+// call logs in and calls the API method, decoding the JSON response in to v.
+func (q *qbittorrent) call(ctx context.Context, method string, form url.Values, v interface{}) error {
+	if err := q.login(ctx); err != nil {
+		return err
+	}
+	body, err := q.do(ctx, method, form)
+	if err != nil || v == nil {
+		return err
+	}
+	return errors.Wrap(json.Unmarshal(body, v), "qbittorrent")
+}
+
+type qbTorrent struct {
+	Hash     string  `json:"hash"`
+	Name     string  `json:"name"`
+	Category string  `json:"category"`
+	Tags     string  `json:"tags"`
+	Ratio    float64 `json:"ratio"`
+	Progress float64 `json:"progress"`
+	State    string  `json:"state"`
+	SavePath string  `json:"save_path"`
+}
+
+type qbFile struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Priority int    `json:"priority"`
+}
+
+// Torrents implements the Client interface.
+func (q *qbittorrent) Torrents(ctx context.Context) ([]Torrent, error) {
+	list := []qbTorrent{}
+	if err := q.call(ctx, "/api/v2/torrents/info", url.Values{}, &list); err != nil {
+		return nil, err
+	}
+	torrents := make([]Torrent, 0, len(list))
+	for _, t := range list {
+		labels := []string{}
+		if t.Category != "" {
+			labels = append(labels, t.Category)
+		}
+		for _, tag := range strings.Split(t.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				labels = append(labels, tag)
+			}
+		}
+		files := []qbFile{}
+		if err := q.call(ctx, "/api/v2/torrents/files", url.Values{"hash": {t.Hash}}, &files); err != nil {
+			return nil, err
+		}
+		torrent := Torrent{
+			Hash:     t.Hash,
+			Name:     t.Name,
+			Labels:   labels,
+			Ratio:    t.Ratio,
+			Complete: t.Progress >= 1,
+			// the upload states a torrent is stopped in when its share
+			// limits are reached (stoppedUP since v5)
+			Seeded: t.State == "pausedUP" || t.State == "stoppedUP",
+			Dir:    t.SavePath,
+		}
+		for _, f := range files {
+			// skip files that are not downloaded
+			if f.Priority == 0 {
+				continue
+			}
+			torrent.Files = append(torrent.Files, File{Name: f.Name, Size: f.Size})
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+// Relocate implements the Client interface.
+func (q *qbittorrent) Relocate(ctx context.Context, hash, dir string) error {
+	return q.call(ctx, "/api/v2/torrents/setLocation", url.Values{
+		"hashes":   {hash},
+		"location": {dir},
+	}, nil)
+}
+
+// Remove implements the Client interface.
+func (q *qbittorrent) Remove(ctx context.Context, hash string) error {
+	return q.call(ctx, "/api/v2/torrents/delete", url.Values{
+		"hashes":      {hash},
+		"deleteFiles": {"false"},
+	}, nil)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+/*
+Package torrent is a minimal client for the qBittorrent Web API and the
+Transmission RPC, supporting only the operations needed to sort completed
+torrents: listing them with their files, relocating, and removing them.
+*/
+package torrent
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Identifier is the Identifiers key of the hash of the torrent an item was
+// downloaded by.
+const Identifier = "torrent-hash"
+
+// Type is a torrent client implementation.
+type Type string
+
+const (
+	QBittorrent  Type = "qbittorrent"
+	Transmission Type = "transmission"
+)
+
+// Config is the connection config for a torrent client.
+type Config struct {
+	// URL of the client's web interface, like http://localhost:8080
+	URL string `mapstructure:"url" description:"url of the client's web interface, like http://localhost:8080"`
+	// User to log in as
+	User string `mapstructure:"user" description:"user to log in as"`
+	// Password to log in with
+	Password string `mapstructure:"password" description:"password to log in with"`
+}
+
+// File is a file in a Torrent.
+type File struct {
+	// Name is the path of the file relative to the Torrent's Dir
+	Name string
+	Size int64
+}
+
+// Torrent is a torrent known to the client.
+type Torrent struct {
+	Hash string
+	Name string
+	// Labels are the qBittorrent category and tags, or the Transmission
+	// labels
+	Labels []string
+	Ratio  float64
+	// Complete is true if all of the wanted files are downloaded
+	Complete bool
+	// Seeded is true if the client has stopped seeding because the
+	// torrent's seeding goals are met
+	Seeded bool
+	// Dir is the directory the torrent's files are saved in
+	Dir   string
+	Files []File
+}
+
+// HasLabel is true if the Torrent has any of the labels.
+func (t *Torrent) HasLabel(labels ...string) bool {
+	for _, want := range labels {
+		for _, l := range t.Labels {
+			if l == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Client is a torrent client.
+type Client interface {
+	// Torrents lists the torrents with their files.
+	Torrents(ctx context.Context) ([]Torrent, error)
+	// Relocate moves the torrent's data to the dir.
+	Relocate(ctx context.Context, hash, dir string) error
+	// Remove removes the torrent, leaving its data.
+	Remove(ctx context.Context, hash string) error
+}
+
+// New returns a Client of the Type.
+func New(t Type, cfg Config) (Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.Errorf("%s: url must be set", t)
+	}
+	switch t {
+	case QBittorrent:
+		return newQBittorrent(cfg, http.DefaultClient)
+	case Transmission:
+		return newTransmission(cfg, http.DefaultClient), nil
+	}
+	return nil, errors.Errorf("unknown torrent client %s", t)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package torrent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+var want = []Torrent{
+	{
+		Hash:     "abc",
+		Name:     "Show.S01E01",
+		Labels:   []string{"tv", "hd"},
+		Ratio:    2,
+		Complete: true,
+		Seeded:   true,
+		Dir:      "/downloads",
+		Files:    []File{{Name: "Show.S01E01/Show.S01E01.mkv", Size: 100}},
+	},
+}
+
+func TestQBittorrent(t *testing.T) {
+	calls := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/auth/login" {
+			if r.FormValue("username") != "admin" || r.FormValue("password") != "pass" {
+				fmt.Fprint(w, "Fails.")
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+			fmt.Fprint(w, "Ok.")
+			return
+		}
+		if c, err := r.Cookie("SID"); err != nil || c.Value != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		calls = append(calls, r.URL.Path+" "+r.FormValue("hashes"))
+		switch r.URL.Path {
+		case "/api/v2/torrents/info":
+			fmt.Fprint(w, `[{"hash":"abc","name":"Show.S01E01","category":"tv","tags":"hd","ratio":2,"progress":1,"state":"pausedUP","save_path":"/downloads"}]`)
+		case "/api/v2/torrents/files":
+			fmt.Fprint(w, `[{"name":"Show.S01E01/Show.S01E01.mkv","size":100,"priority":1},{"name":"Show.S01E01/skipped.nfo","size":1,"priority":0}]`)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(QBittorrent, Config{URL: srv.URL, User: "admin", Password: "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Torrents(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	if err := c.Remove(context.TODO(), "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if calls[len(calls)-1] != "/api/v2/torrents/delete abc" {
+		t.Errorf("got calls %v", calls)
+	}
+}
+
+func TestTransmission(t *testing.T) {
+	methods := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(sessionHeader) != "token" {
+			w.Header().Set(sessionHeader, "token")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if u, p, _ := r.BasicAuth(); u != "admin" || p != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		req := struct {
+			Method    string                 `json:"method"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		methods = append(methods, req.Method)
+		switch req.Method {
+		case "torrent-get":
+			fmt.Fprint(w, `{"result":"success","arguments":{"torrents":[{"hashString":"abc","name":"Show.S01E01","labels":["tv","hd"],"uploadRatio":2,"percentDone":1,"isFinished":true,"downloadDir":"/downloads","files":[{"name":"Show.S01E01/Show.S01E01.mkv","length":100},{"name":"Show.S01E01/skipped.nfo","length":1}],"wanted":[1,0]}]}}`)
+		case "torrent-set-location":
+			if req.Arguments["location"] != "/done" {
+				fmt.Fprint(w, `{"result":"bad location"}`)
+				return
+			}
+			fmt.Fprint(w, `{"result":"success","arguments":{}}`)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(Transmission, Config{URL: srv.URL, User: "admin", Password: "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Torrents(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+	if err := c.Relocate(context.TODO(), "abc", "/done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Relocate(context.TODO(), "abc", "/elsewhere"); err == nil {
+		t.Error("expected an rpc error")
+	}
+	if !reflect.DeepEqual(methods, []string{"torrent-get", "torrent-set-location", "torrent-set-location"}) {
+		t.Errorf("got methods %v", methods)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// sessionHeader carries the Transmission CSRF token.
+const sessionHeader = "X-Transmission-Session-Id"
+
+// transmission is a client for the Transmission RPC.
+type transmission struct {
+	Config
+	http *http.Client
+
+	sync.Mutex
+	session string
+}
+
+func newTransmission(cfg Config, client *http.Client) *transmission {
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	if !strings.HasSuffix(cfg.URL, "/rpc") {
+		cfg.URL += "/transmission/rpc"
+	}
+	return &transmission{Config: cfg, http: client}
+}
+
+type rpcRequest struct {
+	Method    string      `json:"method"`
+	Arguments interface{} `json:"arguments"`
+}
+
+type rpcResponse struct {
+	Result    string          `json:"result"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// call calls the RPC method, decoding the response arguments in to v. The
+// request is retried once with a new session id when the server rejects
+// the current one.
+func (t *transmission) call(ctx context.Context, method string, args, v interface{}) error {
+	b, err := json.Marshal(rpcRequest{method, args})
+	if err != nil {
+		return err
+	}
+	for retry := true; ; retry = false {
+		req, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		if t.User != "" {
+			req.SetBasicAuth(t.User, t.Password)
+		}
+		t.Lock()
+		req.Header.Set(sessionHeader, t.session)
+		t.Unlock()
+		res, err := t.http.Do(req)
+		if err != nil {
+			return errors.Wrap(err, "transmission")
+		}
+		if res.StatusCode == http.StatusConflict && retry {
+			res.Body.Close()
+			t.Lock()
+			t.session = res.Header.Get(sessionHeader)
+			t.Unlock()
+			continue
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return errors.Errorf("transmission: %s: %s", method, res.Status)
+		}
+		rpc := rpcResponse{}
+		if err := json.NewDecoder(res.Body).Decode(&rpc); err != nil {
+			return errors.Wrap(err, "transmission")
+		}
+		if rpc.Result != "success" {
+			return errors.Errorf("transmission: %s: %s", method, rpc.Result)
+		}
+		if v == nil {
+			return nil
+		}
+		return errors.Wrap(json.Unmarshal(rpc.Arguments, v), "transmission")
+	}
+}
+
+type trTorrent struct {
+	HashString  string   `json:"hashString"`
+	Name        string   `json:"name"`
+	Labels      []string `json:"labels"`
+	UploadRatio float64  `json:"uploadRatio"`
+	PercentDone float64  `json:"percentDone"`
+	IsFinished  bool     `json:"isFinished"`
+	DownloadDir string   `json:"downloadDir"`
+	Files       []struct {
+		Name   string `json:"name"`
+		Length int64  `json:"length"`
+	} `json:"files"`
+	Wanted []interface{} `json:"wanted"`
+}
+
+// wanted reads the wanted flag of the file, which is a bool or 0/1
+// depending on the version.
+func (t *trTorrent) wanted(i int) bool {
+	if i >= len(t.Wanted) {
+		return true
+	}
+	switch w := t.Wanted[i].(type) {
+	case bool:
+		return w
+	case float64:
+		return w != 0
+	}
+	return true
+}
+
+// Torrents implements the Client interface.
+func (t *transmission) Torrents(ctx context.Context) ([]Torrent, error) {
+	res := struct {
+		Torrents []trTorrent `json:"torrents"`
+	}{}
+	if err := t.call(ctx, "torrent-get", map[string]interface{}{
+		"fields": []string{"hashString", "name", "labels", "uploadRatio", "percentDone", "isFinished", "downloadDir", "files", "wanted"},
+	}, &res); err != nil {
+		return nil, err
+	}
+	torrents := make([]Torrent, 0, len(res.Torrents))
+	for _, tr := range res.Torrents {
+		torrent := Torrent{
+			Hash:     tr.HashString,
+			Name:     tr.Name,
+			Labels:   tr.Labels,
+			Ratio:    tr.UploadRatio,
+			Complete: tr.PercentDone >= 1,
+			Seeded:   tr.IsFinished,
+			Dir:      tr.DownloadDir,
+		}
+		for i, f := range tr.Files {
+			if tr.wanted(i) {
+				torrent.Files = append(torrent.Files, File{Name: f.Name, Size: f.Length})
+			}
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+// Relocate implements the Client interface.
+func (t *transmission) Relocate(ctx context.Context, hash, dir string) error {
+	return t.call(ctx, "torrent-set-location", map[string]interface{}{
+		"ids":      []string{hash},
+		"location": dir,
+		"move":     true,
+	}, nil)
+}
+
+// Remove implements the Client interface.
+func (t *transmission) Remove(ctx context.Context, hash string) error {
+	return t.call(ctx, "torrent-remove", map[string]interface{}{
+		"ids":               []string{hash},
+		"delete-local-data": false,
+	}, nil)
+}
@@ -0,0 +1,311 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package trakt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/go-trakt"
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+	"github.com/rbtr/pachinko/types/metadata/video"
+)
+
+const (
+	collectionPath = "/sync/collection"
+	// DefaultBatchSize is the number of items sent in a collection request.
+	DefaultBatchSize = 100
+)
+
+// Metadata is the media details of a collected item.
+type Metadata struct {
+	MediaType     string `json:"media_type,omitempty"`
+	Resolution    string `json:"resolution,omitempty"`
+	HDR           string `json:"hdr,omitempty"`
+	Audio         string `json:"audio,omitempty"`
+	AudioChannels string `json:"audio_channels,omitempty"`
+}
+
+// CollectedMovie is a movie in a collection request.
+type CollectedMovie struct {
+	trakt.Movie
+	Metadata
+}
+
+// CollectedEpisode is an episode in a collection request, identified by its
+// ids or, in a show, by its number.
+type CollectedEpisode struct {
+	Number int        `json:"number,omitempty"`
+	IDs    *trakt.IDs `json:"ids,omitempty"`
+	Metadata
+}
+
+// CollectedSeason is a season in a collection request.
+type CollectedSeason struct {
+	Number   int                `json:"number"`
+	Episodes []CollectedEpisode `json:"episodes,omitempty"`
+}
+
+// CollectedShow is a show in a collection request.
+type CollectedShow struct {
+	trakt.Show
+	Seasons []CollectedSeason `json:"seasons,omitempty"`
+}
+
+// CollectionBody is the body of a request to add items to, or remove them
+// from, the collection.
+type CollectionBody struct {
+	Movies   []CollectedMovie   `json:"movies,omitempty"`
+	Shows    []CollectedShow    `json:"shows,omitempty"`
+	Episodes []CollectedEpisode `json:"episodes,omitempty"`
+}
+
+// Counts of the items affected by a collection request.
+type Counts struct {
+	Movies   int `json:"movies"`
+	Episodes int `json:"episodes"`
+}
+
+// CollectionResult is the result of a collection request.
+type CollectionResult struct {
+	Added    Counts         `json:"added"`
+	Updated  Counts         `json:"updated"`
+	Existing Counts         `json:"existing"`
+	Deleted  Counts         `json:"deleted"`
+	NotFound CollectionBody `json:"not_found"`
+}
+
+// Len is the number of movies and episodes in the body.
+func (b *CollectionBody) Len() int {
+	n := len(b.Movies) + len(b.Episodes)
+	for _, s := range b.Shows {
+		for _, season := range s.Seasons {
+			n += len(season.Episodes)
+		}
+	}
+	return n
+}
+
+// addShowEpisode adds the episode to the season of the show in the body.
+func (b *CollectionBody) addShowEpisode(show trakt.Show, season int, e CollectedEpisode) {
+	i := 0
+	for ; i < len(b.Shows) && b.Shows[i].IDs != show.IDs; i++ {
+	}
+	if i == len(b.Shows) {
+		b.Shows = append(b.Shows, CollectedShow{Show: show})
+	}
+	s := &b.Shows[i]
+	j := 0
+	for ; j < len(s.Seasons) && s.Seasons[j].Number != season; j++ {
+	}
+	if j == len(s.Seasons) {
+		s.Seasons = append(s.Seasons, CollectedSeason{Number: season})
+	}
+	s.Seasons[j].Episodes = append(s.Seasons[j].Episodes, e)
+}
+
+// Split splits the body in to bodies of up to size items each.
+func (b *CollectionBody) Split(size int) []*CollectionBody {
+	batches := []*CollectionBody{}
+	cur := &CollectionBody{}
+	next := func() {
+		if cur.Len() >= size {
+			batches = append(batches, cur)
+			cur = &CollectionBody{}
+		}
+	}
+	for _, m := range b.Movies {
+		cur.Movies = append(cur.Movies, m)
+		next()
+	}
+	for _, e := range b.Episodes {
+		cur.Episodes = append(cur.Episodes, e)
+		next()
+	}
+	for _, s := range b.Shows {
+		for _, season := range s.Seasons {
+			for _, e := range season.Episodes {
+				cur.addShowEpisode(s.Show, season.Number, e)
+				next()
+			}
+		}
+	}
+	if cur.Len() > 0 {
+		batches = append(batches, cur)
+	}
+	return batches
+}
+
+// ids reads the trakt ids from the item's identifiers, using the keys of the
+// tvdb and tmdb ids given.
+func ids(m types.Item, tvdbKey, tmdbKey string) trakt.IDs {
+	ids := trakt.IDs{
+		IMDB: m.Identifiers["imdb"],
+	}
+	ids.Trakt, _ = strconv.Atoi(m.Identifiers["trakt"])
+	ids.TVDB, _ = strconv.Atoi(m.Identifiers[tvdbKey])
+	ids.TMDb, _ = strconv.Atoi(m.Identifiers[tmdbKey])
+	return ids
+}
+
+// NewMetadata converts the video metadata to the trakt media details.
+func NewMetadata(md video.Metadata) Metadata {
+	out := Metadata{
+		MediaType: map[string]string{
+			"bluray": "bluray",
+			"dvd":    "dvd",
+			"hdtv":   "digital",
+			"web":    "digital",
+		}[md.Source],
+		HDR: map[string]string{
+			"dolby vision": "dolby_vision",
+			"hdr10":        "hdr10",
+			"hdr10+":       "hdr10_plus",
+			"hlg":          "hlg",
+		}[md.HDR],
+		Audio: map[string]string{
+			"aac":    "aac",
+			"ac3":    "dolby_digital",
+			"eac3":   "dolby_digital_plus",
+			"dts":    "dts",
+			"dts-hd": "dts_ma",
+			"flac":   "flac",
+			"truehd": "dolby_truehd",
+		}[md.AudioFormat],
+	}
+	switch h := md.Resolution.Height; {
+	case h >= 2160:
+		out.Resolution = "uhd_4k"
+	case h >= 1080:
+		out.Resolution = "hd_1080p"
+	case h >= 720:
+		out.Resolution = "hd_720p"
+	case h >= 576:
+		out.Resolution = "sd_576p"
+	case h > 0:
+		out.Resolution = "sd_480p"
+	}
+	if md.AudioChannels.FullRange > 0 {
+		out.AudioChannels = md.AudioChannels.String()
+	}
+	return out
+}
+
+// Add adds the item to the body. Movies are identified by their tmdb, imdb,
+// or trakt ids and episodes by theirs, or by their show's tvdb id and their
+// season and episode number. Items that can't be identified aren't added.
+func (b *CollectionBody) Add(m types.Item) bool {
+	md := NewMetadata(m.VideoMetadata)
+	switch m.MediaType {
+	case movie.Movie:
+		id := ids(m, "", "tmdb")
+		if id == (trakt.IDs{}) {
+			return false
+		}
+		b.Movies = append(b.Movies, CollectedMovie{
+			Movie: trakt.Movie{
+				Title: m.MovieMetadata.Title,
+				Year:  m.MovieMetadata.ReleaseYear,
+				IDs:   id,
+			},
+			Metadata: md,
+		})
+		return true
+	case tv.TV:
+		if id := ids(m, "tvdb", "tmdb"); id != (trakt.IDs{}) {
+			b.Episodes = append(b.Episodes, CollectedEpisode{IDs: &id, Metadata: md})
+			return true
+		}
+		show := trakt.IDs{}
+		show.TVDB, _ = strconv.Atoi(m.Identifiers["tvdb-series"])
+		if show.TVDB == 0 || m.TVMetadata.Episode.Number == 0 {
+			return false
+		}
+		b.addShowEpisode(
+			trakt.Show{Title: m.TVMetadata.Name, Year: m.TVMetadata.ReleaseYear, IDs: show},
+			m.TVMetadata.Season.Number,
+			CollectedEpisode{Number: m.TVMetadata.Episode.Number, Metadata: md},
+		)
+		return true
+	}
+	return false
+}
+
+// do sends the request to the trakt api and decodes the response in to the
+// result.
+func (t *Trakt) do(ctx context.Context, method, p string, body, result interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	uri := *t.BaseURL
+	uri.Path = path.Join(uri.Path, p)
+	req, err := http.NewRequest(method, uri.String(), &buf)
+	if err != nil {
+		return err
+	}
+	t.SetHeaders(req)
+	resp, err := t.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("trakt: %s %s: %s", method, p, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// AddToCollection adds the items in the body to the collection.
+func (t *Trakt) AddToCollection(ctx context.Context, body *CollectionBody) (*CollectionResult, error) {
+	result := &CollectionResult{}
+	return result, t.do(ctx, http.MethodPost, collectionPath, body, result)
+}
+
+// RemoveFromCollection removes the items in the body from the collection.
+func (t *Trakt) RemoveFromCollection(ctx context.Context, body *CollectionBody) (*CollectionResult, error) {
+	result := &CollectionResult{}
+	return result, t.do(ctx, http.MethodPost, collectionPath+"/remove", body, result)
+}
+
+// CollectionMovie is a movie in the collection.
+type CollectionMovie struct {
+	Movie trakt.Movie `json:"movie"`
+}
+
+// CollectionShow is a show in the collection, with its collected episodes.
+type CollectionShow struct {
+	Show    trakt.Show `json:"show"`
+	Seasons []struct {
+		Number   int `json:"number"`
+		Episodes []struct {
+			Number int `json:"number"`
+		} `json:"episodes"`
+	} `json:"seasons"`
+}
+
+// CollectedMovies gets the movies in the collection.
+func (t *Trakt) CollectedMovies(ctx context.Context) ([]CollectionMovie, error) {
+	movies := []CollectionMovie{}
+	return movies, t.do(ctx, http.MethodGet, collectionPath+"/movies", nil, &movies)
+}
+
+// CollectedShows gets the shows in the collection.
+func (t *Trakt) CollectedShows(ctx context.Context) ([]CollectionShow, error) {
+	shows := []CollectionShow{}
+	return shows, t.do(ctx, http.MethodGet, collectionPath+"/shows", nil, &shows)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package trakt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rbtr/go-trakt"
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+	"github.com/rbtr/pachinko/types/metadata/video"
+)
+
+func tvItem(name string, season, episode int, ids map[string]string) types.Item {
+	m := types.Item{MediaType: tv.TV, Identifiers: ids, SourcePath: fmt.Sprintf("/tv/%s S%02dE%02d.mkv", name, season, episode)}
+	m.TVMetadata.Name = name
+	m.TVMetadata.Season.Number = season
+	m.TVMetadata.Episode.Number = episode
+	return m
+}
+
+func movieItem(title string, year int, ids map[string]string) types.Item {
+	m := types.Item{MediaType: movie.Movie, Identifiers: ids, SourcePath: fmt.Sprintf("/movies/%s (%d).mkv", title, year)}
+	m.MovieMetadata.Title = title
+	m.MovieMetadata.ReleaseYear = year
+	return m
+}
+
+func TestCollectionBody_Add(t *testing.T) {
+	b := &CollectionBody{}
+	heat := movieItem("Heat", 1995, map[string]string{"imdb": "tt0113277"})
+	heat.VideoMetadata = video.Metadata{
+		AudioChannels: video.AudioChannels{FullRange: 5, LimitedRange: 1},
+		AudioFormat:   "dts-hd",
+		HDR:           "dolby vision",
+		Resolution:    video.Resolution{Width: 3840, Height: 2160},
+		Source:        "bluray",
+	}
+	for _, m := range []types.Item{
+		heat,
+		tvItem("Mr Robot", 1, 1, map[string]string{"tvdb": "5141261"}),
+		tvItem("Mr Robot", 1, 2, map[string]string{"tvdb-series": "289590"}),
+		tvItem("Mr Robot", 2, 1, map[string]string{"tvdb-series": "289590"}),
+	} {
+		if !b.Add(m) {
+			t.Errorf("failed to add %s", m.SourcePath)
+		}
+	}
+	if b.Add(movieItem("Alien", 1979, map[string]string{})) {
+		t.Error("added a movie with no ids")
+	}
+	if b.Add(tvItem("Fargo", 1, 1, map[string]string{})) {
+		t.Error("added an episode with no ids")
+	}
+
+	got, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"movies":[{"title":"Heat","year":1995,"ids":{"imdb":"tt0113277"},"media_type":"bluray","resolution":"uhd_4k","hdr":"dolby_vision","audio":"dts_ma","audio_channels":"5.1"}],` +
+		`"shows":[{"title":"Mr Robot","ids":{"tvdb":289590},"seasons":[{"number":1,"episodes":[{"number":2}]},{"number":2,"episodes":[{"number":1}]}]}],` +
+		`"episodes":[{"ids":{"tvdb":5141261}}]}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	batches := b.Split(2)
+	if len(batches) != 2 || batches[0].Len() != 2 || batches[1].Len() != 2 {
+		t.Fatalf("got batches %+v", batches)
+	}
+	if len(batches[1].Shows) != 1 || len(batches[1].Shows[0].Seasons) != 2 {
+		t.Errorf("expected the show's episodes to be regrouped, got %+v", batches[1].Shows)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	movies := []CollectionMovie{}
+	shows := []CollectionShow{}
+	if err := json.Unmarshal([]byte(`[
+		{"movie": {"title": "Heat", "year": 1995, "ids": {"trakt": 1, "tmdb": 949, "imdb": "tt0113277"}}},
+		{"movie": {"title": "Alien", "year": 1979, "ids": {"trakt": 2, "tmdb": 348}}},
+		{"movie": {"title": "Ronin", "year": 1998, "ids": {"trakt": 3, "tmdb": 8195}}}
+	]`), &movies); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`[
+		{"show": {"title": "Mr. Robot", "year": 2015, "ids": {"trakt": 4, "tvdb": 289590}},
+		 "seasons": [{"number": 1, "episodes": [{"number": 1}, {"number": 2}]}]},
+		{"show": {"title": "Fargo", "year": 2014, "ids": {"trakt": 5, "tvdb": 269613}},
+		 "seasons": [{"number": 1, "episodes": [{"number": 1}]}]},
+		{"show": {"title": "Dark", "year": 2017, "ids": {"trakt": 6, "tvdb": 334824}},
+		 "seasons": [{"number": 1, "episodes": [{"number": 1}]}]}
+	]`), &shows); err != nil {
+		t.Fatal(err)
+	}
+	items := []types.Item{
+		// by id
+		movieItem("Heat", 1995, map[string]string{"tmdb": "949"}),
+		// by title and year
+		movieItem("alien", 1979, map[string]string{}),
+		// missing from the collection
+		movieItem("Tenet", 2020, map[string]string{"tmdb": "577922"}),
+		// unidentified
+		movieItem("Home Movie", 2019, map[string]string{}),
+		// by series id
+		tvItem("Mr Robot", 1, 1, map[string]string{"tvdb": "5141261", "tvdb-series": "289590"}),
+		// missing from the collection
+		tvItem("Mr Robot", 1, 3, map[string]string{"tvdb": "5141263", "tvdb-series": "289590"}),
+		// by name
+		tvItem("fargo", 1, 1, map[string]string{}),
+		// unidentified, so none of the show's episodes are removed
+		tvItem("Dark", 1, 0, map[string]string{}),
+	}
+
+	r := reconcile(items, movies, shows)
+	if len(r.Add.Movies) != 1 || r.Add.Movies[0].IDs.TMDb != 577922 {
+		t.Errorf("got added movies %+v", r.Add.Movies)
+	}
+	if len(r.Add.Episodes) != 1 || r.Add.Episodes[0].IDs.TVDB != 5141263 {
+		t.Errorf("got added episodes %+v", r.Add.Episodes)
+	}
+	if len(r.Unidentified) != 2 || r.Unidentified[0].MovieMetadata.Title != "Home Movie" {
+		t.Errorf("got unidentified %+v", r.Unidentified)
+	}
+	if len(r.Remove.Movies) != 1 || r.Remove.Movies[0].IDs.Trakt != 3 {
+		t.Errorf("got removed movies %+v", r.Remove.Movies)
+	}
+	if len(r.Remove.Shows) != 1 || r.Remove.Shows[0].IDs.Trakt != 4 ||
+		len(r.Remove.Shows[0].Seasons) != 1 || r.Remove.Shows[0].Seasons[0].Episodes[0].Number != 2 {
+		t.Errorf("got removed shows %+v", r.Remove.Shows)
+	}
+}
+
+func TestTrakt_collection(t *testing.T) {
+	requests := map[string]CollectionBody{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/sync/collection", "/sync/collection/remove":
+			body := CollectionBody{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			requests[r.URL.Path] = body
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"added": {"movies": %d}, "deleted": {"episodes": %d}}`, len(body.Movies), len(body.Episodes))
+		case "/sync/collection/movies":
+			fmt.Fprint(w, `[{"movie": {"title": "Heat", "year": 1995, "ids": {"tmdb": 949}}}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewTrakt(&Auth{AccessToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.BaseURL, err = url.Parse(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.TODO()
+	body := &CollectionBody{Movies: []CollectedMovie{{Movie: trakt.Movie{IDs: trakt.IDs{TMDb: 949}}}}}
+	res, err := client.AddToCollection(ctx, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Added.Movies != 1 || requests["/sync/collection"].Movies[0].IDs.TMDb != 949 {
+		t.Errorf("got %+v, %+v", res, requests)
+	}
+	body = &CollectionBody{Episodes: []CollectedEpisode{{IDs: &trakt.IDs{TVDB: 1}}}}
+	if res, err = client.RemoveFromCollection(ctx, body); err != nil {
+		t.Fatal(err)
+	}
+	if res.Deleted.Episodes != 1 {
+		t.Errorf("got %+v", res)
+	}
+	movies, err := client.CollectedMovies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != 1 || movies[0].Movie.Title != "Heat" {
+		t.Errorf("got %+v", movies)
+	}
+	if _, err := client.CollectedShows(ctx); err == nil {
+		t.Error("expected error for not found")
+	}
+}
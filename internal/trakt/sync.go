@@ -0,0 +1,135 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package trakt
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/rbtr/go-trakt"
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+// Reconciliation is the changes that make the collection match the media in
+// the library.
+type Reconciliation struct {
+	// Add the media missing from the collection
+	Add *CollectionBody
+	// Remove the collected media missing from the library, except for the
+	// episodes of shows with unidentified media in the library, which could
+	// be the missing episodes
+	Remove *CollectionBody
+	// Unidentified media in the library that can't be added
+	Unidentified []types.Item
+}
+
+// Reconcile compares the items in the library with the collection.
+func (t *Trakt) Reconcile(ctx context.Context, items []types.Item) (*Reconciliation, error) {
+	movies, err := t.CollectedMovies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shows, err := t.CollectedShows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return reconcile(items, movies, shows), nil
+}
+
+// sameMovie is whether the item is the collected movie, by any of their ids
+// or by their title and year.
+func sameMovie(m types.Item, c trakt.Movie) bool {
+	id := ids(m, "", "tmdb")
+	switch {
+	case id.TMDb != 0 && id.TMDb == c.IDs.TMDb,
+		id.IMDB != "" && id.IMDB == c.IDs.IMDB,
+		id.Trakt != 0 && id.Trakt == c.IDs.Trakt:
+		return true
+	}
+	return c.Year == m.MovieMetadata.ReleaseYear && strings.EqualFold(c.Title, m.MovieMetadata.Title)
+}
+
+// sameShow is whether the item is an episode of the collected show, by the
+// show's tvdb id or by its name.
+func sameShow(m types.Item, c trakt.Show) bool {
+	if id, _ := strconv.Atoi(m.Identifiers["tvdb-series"]); id != 0 && id == c.IDs.TVDB {
+		return true
+	}
+	return strings.EqualFold(c.Title, m.TVMetadata.Name)
+}
+
+func reconcile(items []types.Item, movies []CollectionMovie, shows []CollectionShow) *Reconciliation {
+	r := &Reconciliation{Add: &CollectionBody{}, Remove: &CollectionBody{}, Unidentified: []types.Item{}}
+	// the collected movies, and episodes by show, season, and number, that
+	// are in the library
+	haveMovies := make([]bool, len(movies))
+	type episode struct{ show, season, number int }
+	haveEpisodes := map[episode]bool{}
+	// the collected shows with episodes in the library that can't be matched
+	unidentifiedShows := make([]bool, len(shows))
+
+	for _, m := range items {
+		found := false
+		switch m.MediaType {
+		case movie.Movie:
+			for i, c := range movies {
+				if sameMovie(m, c.Movie) {
+					haveMovies[i], found = true, true
+				}
+			}
+		case tv.TV:
+			for i, c := range shows {
+				if !sameShow(m, c.Show) {
+					continue
+				}
+				for _, season := range c.Seasons {
+					for _, e := range season.Episodes {
+						if season.Number == m.TVMetadata.Season.Number && e.Number == m.TVMetadata.Episode.Number {
+							haveEpisodes[episode{i, season.Number, e.Number}], found = true, true
+						}
+					}
+				}
+			}
+		default:
+			continue
+		}
+		if found || r.Add.Add(m) {
+			continue
+		}
+		r.Unidentified = append(r.Unidentified, m)
+		if m.MediaType == tv.TV {
+			for i, c := range shows {
+				if sameShow(m, c.Show) {
+					unidentifiedShows[i] = true
+				}
+			}
+		}
+	}
+
+	for i, c := range movies {
+		if !haveMovies[i] {
+			r.Remove.Movies = append(r.Remove.Movies, CollectedMovie{Movie: c.Movie})
+		}
+	}
+	for i, c := range shows {
+		if unidentifiedShows[i] {
+			continue
+		}
+		for _, season := range c.Seasons {
+			for _, e := range season.Episodes {
+				if !haveEpisodes[episode{i, season.Number, e.Number}] {
+					r.Remove.addShowEpisode(c.Show, season.Number, CollectedEpisode{Number: e.Number})
+				}
+			}
+		}
+	}
+	return r
+}
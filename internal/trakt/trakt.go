@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"time"
 
@@ -69,6 +70,7 @@ func WriteAuthFile(path string, auth *Auth) error {
 type Trakt struct {
 	*trakt.Client
 	auth *Auth
+	http *http.Client
 }
 
 func NewTrakt(auth *Auth) (*Trakt, error) {
@@ -82,7 +84,24 @@ func NewTrakt(auth *Auth) (*Trakt, error) {
 	if auth.AccessToken != "" {
 		client.SetAuthorization(auth.AccessToken)
 	}
-	return &Trakt{client, auth}, err
+	return &Trakt{client, auth, http.DefaultClient}, err
+}
+
+// Login reads the authfile, creates a client, refreshes the credentials, and
+// writes them back to the authfile.
+func Login(ctx context.Context, authfile string) (*Trakt, error) {
+	auth, err := ReadAuthFile(authfile)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewTrakt(auth)
+	if err != nil {
+		return nil, err
+	}
+	if auth, err = client.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return client, WriteAuthFile(authfile, auth)
 }
 
 // Authorize authorizes the client using 2-legged oauth.
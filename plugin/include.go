@@ -11,7 +11,10 @@ import (
 	// blank includes
 	_ "github.com/rbtr/pachinko/plugin/input"
 	_ "github.com/rbtr/pachinko/plugin/output"
+	_ "github.com/rbtr/pachinko/plugin/processor/external"
 	_ "github.com/rbtr/pachinko/plugin/processor/intra"
 	_ "github.com/rbtr/pachinko/plugin/processor/post"
 	_ "github.com/rbtr/pachinko/plugin/processor/pre"
+	_ "github.com/rbtr/pachinko/plugin/processor/script"
+	_ "github.com/rbtr/pachinko/plugin/processor/wasm"
 )
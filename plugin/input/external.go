@@ -0,0 +1,65 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"time"
+
+	"github.com/rbtr/pachinko/internal/external"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExternalInput runs an executable as an input, pushing the items it sends
+// in to the pipeline until it exits.
+type ExternalInput struct {
+	external.Command `mapstructure:",squash"`
+	// Label to tag the items with, if the executable doesn't set a source
+	Label string `mapstructure:"label" description:"label to tag the items with, if the executable doesn't set a source"`
+
+	proc *external.Process
+}
+
+func (p *ExternalInput) Init(ctx context.Context) error {
+	log.Trace("external_input: initializing")
+	var err error
+	if p.proc, err = p.Start(ctx, "external_input", external.Input, false); err != nil {
+		return err
+	}
+	// inputs don't receive items
+	return p.proc.CloseSend()
+}
+
+// Consume pushes the items from the executable in to the pipeline.
+func (p *ExternalInput) Consume(sink chan<- types.Item) {
+	log.Trace("started external_input")
+	count := 0
+	p.proc.Receive(func(m types.Item) {
+		if m.Source == "" {
+			m.Source = p.Label
+		}
+		sink <- m
+		count++
+	})
+	if err := p.proc.Wait(); err != nil {
+		log.Error(err)
+	}
+	log.Debugf("external_input: ingested %d items", count)
+}
+
+func init() {
+	Register("external", func() Input {
+		return &ExternalInput{
+			Command: external.Command{
+				Timeout: 10 * time.Second,
+			},
+			Label: "external",
+		}
+	})
+}
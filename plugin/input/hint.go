@@ -0,0 +1,215 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata"
+	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+	log "github.com/sirupsen/logrus"
+)
+
+// Hint is a path that an input is told to ingest, with optional hints about
+// what it is. Hints with a media type bypass the path metadata
+// pre-processors.
+type Hint struct {
+	Path      string             `json:"path"`
+	Category  types.Category     `json:"category"`
+	MediaType metadata.MediaType `json:"media-type"`
+	// Title of the movie or name of the show
+	Title string `json:"title"`
+	Year  int    `json:"year"`
+	// Season and Episode are only set when they are in the hint, so that
+	// season 0 can be hinted
+	Season  *int `json:"season"`
+	Episode *int `json:"episode"`
+	// Identifiers to add to the item, tvdb, tmdb, and imdb can also be set
+	// directly
+	Identifiers map[string]string `json:"identifiers"`
+	TVDB        hintID            `json:"tvdb"`
+	TMDB        hintID            `json:"tmdb"`
+	IMDB        hintID            `json:"imdb"`
+}
+
+// hintID is an id that can be sent as a JSON string or number, or null for
+// none.
+type hintID string
+
+func (id *hintID) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*id = ""
+		return nil
+	}
+	*id = hintID(strings.Trim(string(b), `"`))
+	return nil
+}
+
+// fields are the metadata fields that are in the hint.
+func (h *Hint) fields() []string {
+	var fields []string
+	if h.Title != "" {
+		fields = append(fields, "title")
+	}
+	if h.Year != 0 {
+		fields = append(fields, "year")
+	}
+	if h.Season != nil && h.MediaType == tv.TV {
+		fields = append(fields, "season")
+	}
+	if h.Episode != nil && h.MediaType == tv.TV {
+		fields = append(fields, "episode")
+	}
+	return fields
+}
+
+// apply sets the hints on the item.
+func (h *Hint) apply(m *types.Item) {
+	if h.Category != types.Unknown {
+		m.Category = h.Category
+	}
+	switch h.MediaType {
+	case tv.TV:
+		m.TVMetadata.Name = h.Title
+		m.TVMetadata.ReleaseYear = h.Year
+		if h.Season != nil {
+			m.TVMetadata.Season.Number = *h.Season
+		}
+		if h.Episode != nil {
+			m.TVMetadata.Episode.Number = *h.Episode
+		}
+		m.HintedFields = h.fields()
+	case movie.Movie:
+		m.MovieMetadata.Title = h.Title
+		m.MovieMetadata.ReleaseYear = h.Year
+		m.HintedFields = h.fields()
+	}
+	if h.MediaType != "" {
+		m.MediaType = h.MediaType
+		m.Hinted = true
+	}
+	for k, v := range h.Identifiers {
+		m.Identifiers[k] = v
+	}
+	for k, v := range map[string]hintID{"tvdb": h.TVDB, "tmdb": h.TMDB, "imdb": h.IMDB} {
+		if v != "" {
+			m.Identifiers[k] = string(v)
+		}
+	}
+}
+
+// consume pushes the hinted path in to the pipeline. Directories are walked
+// with the walker and the hints are applied to everything in them.
+func (h *Hint) consume(walker *FilePathInput, label string, sink chan<- types.Item) int {
+	info, err := os.Stat(h.Path)
+	if err != nil {
+		log.Error(err)
+		return 0
+	}
+	if !info.IsDir() {
+		m := types.Item{
+			FileType:    types.File,
+			Identifiers: map[string]string{},
+			ModTime:     info.ModTime(),
+			Size:        info.Size(),
+			Source:      label,
+			SourcePath:  h.Path,
+		}
+		h.apply(&m)
+		log.Infof("found hinted file: %s", m.SourcePath)
+		sink <- m
+		return 1
+	}
+	count := 0
+	items := make(chan types.Item)
+	go func() {
+		walker.consume(SrcDir{Path: h.Path, Label: label}, items)
+		close(items)
+	}()
+	for m := range items {
+		h.apply(&m)
+		sink <- m
+		count++
+	}
+	return count
+}
+
+// ReadHints reads JSON hints, as objects, arrays of objects, or JSON lines,
+// or newline-delimited paths. The format is detected from the first
+// character.
+func ReadHints(r io.Reader) ([]Hint, error) {
+	br := bufio.NewReader(r)
+	hints := []Hint{}
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return hints, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if first == '{' || first == '[' {
+		dec := json.NewDecoder(br)
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if bytes.HasPrefix(raw, []byte("[")) {
+				list := []Hint{}
+				if err := json.Unmarshal(raw, &list); err != nil {
+					return nil, err
+				}
+				hints = append(hints, list...)
+				continue
+			}
+			hint := Hint{}
+			if err := json.Unmarshal(raw, &hint); err != nil {
+				return nil, err
+			}
+			hints = append(hints, hint)
+		}
+	} else {
+		scanner := bufio.NewScanner(br)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				hints = append(hints, Hint{Path: line})
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	for i, h := range hints {
+		if h.Path == "" {
+			return nil, errors.Errorf("hint %d has no path", i)
+		}
+	}
+	return hints, nil
+}
+
+// firstNonSpace peeks at the first non-whitespace byte of the reader.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return b, br.UnreadByte()
+		}
+	}
+}
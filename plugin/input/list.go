@@ -0,0 +1,81 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListInput reads a list of paths from a file or stdin and pushes them in
+// to the pipeline, so that other tools can drive pachinko.
+//
+// The list is newline-delimited paths or JSON lines of hints (see Hint).
+// Directories are walked like the filepath input does with its defaults.
+type ListInput struct {
+	// File to read the list from, empty or - is stdin
+	File string `mapstructure:"file" description:"file to read the list from, empty or - is stdin"`
+	// Label to tag the items with
+	Label string `mapstructure:"label" description:"label to tag the items with"`
+
+	hints  []Hint
+	stdin  io.Reader
+	walker *FilePathInput
+}
+
+// Init reads the list.
+func (p *ListInput) Init(ctx context.Context) error {
+	p.walker = &FilePathInput{
+		Exclude:    []string{"*.part", "*.!qB", "*.!ut", "*.crdownload"},
+		SkipHidden: true,
+	}
+	if err := p.walker.Init(ctx); err != nil {
+		return err
+	}
+	r := p.stdin
+	if r == nil {
+		r = os.Stdin
+	}
+	if p.File != "" && p.File != "-" {
+		f, err := os.Open(p.File)
+		if err != nil {
+			return errors.Wrap(err, "list_input")
+		}
+		defer f.Close()
+		r = f
+	}
+	var err error
+	if p.hints, err = ReadHints(r); err != nil {
+		return errors.Wrap(err, "list_input: error reading list")
+	}
+	return nil
+}
+
+// Consume pushes the listed paths in to the pipeline.
+func (p *ListInput) Consume(sink chan<- types.Item) {
+	log.Trace("started list_input")
+	count := 0
+	for _, hint := range p.hints {
+		count += hint.consume(p.walker, p.Label, sink)
+	}
+	log.Debugf("list_input: ingested %d files", count)
+}
+
+func init() {
+	Register("list", func() Input {
+		return &ListInput{
+			File:  "-",
+			Label: "list",
+		}
+	})
+}
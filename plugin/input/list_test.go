@@ -0,0 +1,96 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+func TestReadHints(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []Hint
+	}{
+		{"empty", " \n", []Hint{}},
+		{"paths", "/a.mkv\n\n /b.mkv \n", []Hint{{Path: "/a.mkv"}, {Path: "/b.mkv"}}},
+		{
+			"json lines",
+			"{\"path\": \"/a.mkv\", \"tvdb\": 1}\n{\"path\": \"/b.mkv\", \"tmdb\": \"2\"}\n",
+			[]Hint{{Path: "/a.mkv", TVDB: "1"}, {Path: "/b.mkv", TMDB: "2"}},
+		},
+		{"null ids", `{"path": "/a.mkv", "tvdb": null, "imdb": "tt1"}`, []Hint{{Path: "/a.mkv", IMDB: "tt1"}}},
+		{"json array", `[{"path": "/a.mkv"}, {"path": "/b.mkv"}]`, []Hint{{Path: "/a.mkv"}, {Path: "/b.mkv"}}},
+		{"season 0", `{"path": "/a.mkv", "season": 0}`, []Hint{{Path: "/a.mkv", Season: new(int)}}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadHints(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+	if _, err := ReadHints(strings.NewReader(`{"title": "no path"}`)); err == nil {
+		t.Error("expected an error for a hint with no path")
+	}
+}
+
+func TestListInput(t *testing.T) {
+	dir := writeTree(t, map[string]int{"file.mkv": 1})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.mkv")
+
+	list := `{"path": "` + path + `", "category": "video", "media-type": "tv", "title": "Show", "season": 1, "episode": 2, "identifiers": {"tvdb": "3"}}`
+	p := &ListInput{Label: "list", stdin: strings.NewReader(list)}
+	if err := p.Init(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	sink := make(chan types.Item)
+	go func() {
+		p.Consume(sink)
+		close(sink)
+	}()
+	got := []types.Item{}
+	for m := range sink {
+		got = append(got, m)
+	}
+	want := types.Item{
+		Category:     types.Video,
+		FileType:     types.File,
+		Hinted:       true,
+		HintedFields: []string{"title", "season", "episode"},
+		Identifiers:  map[string]string{"tvdb": "3"},
+		MediaType:    tv.TV,
+		Size:         1,
+		Source:       "list",
+		SourcePath:   path,
+	}
+	want.TVMetadata.Name = "Show"
+	want.TVMetadata.Season.Number = 1
+	want.TVMetadata.Episode.Number = 2
+	if len(got) != 1 {
+		t.Fatalf("got %d items", len(got))
+	}
+	got[0].ModTime = want.ModTime
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %#v, want %#v", got[0], want)
+	}
+}
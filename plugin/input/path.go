@@ -11,60 +11,272 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/filesystem"
 	"github.com/rbtr/pachinko/types"
 	log "github.com/sirupsen/logrus"
 )
 
-// FilePathInput walks a directory [src-dir], pushing everything in
-// in that directory tree in to the pipeline.
+// SrcDir is a labeled root directory.
+type SrcDir struct {
+	// Path the directory to ingest
+	Path string `mapstructure:"path" description:"the directory to ingest"`
+	// Label to tag the items found in the directory with, defaults to the path
+	Label string `mapstructure:"label" description:"label to tag the items found in the directory with, defaults to the path"`
+}
+
+// FilePathInput walks directories [src-dir, src-dirs], pushing everything in
+// in those directory trees in to the pipeline.
+//
+// The walk can be narrowed with include/exclude globs, a minimum file size,
+// a minimum age since last modification, a maximum depth, and by skipping
+// hidden files. Globs are matched against both the file name and the path
+// relative to the root directory.
+//
+// The directories can be on a remote filesystem over SFTP, in which case the
+// items' source paths are sftp:// URLs.
 type FilePathInput struct {
-	// SrcDir the directory to ingest
-	SrcDir string `mapstructure:"src-dir"`
+	// SrcDir the directory to ingest, unless SrcDirs are set
+	SrcDir string `mapstructure:"src-dir" description:"the directory to ingest, unless src-dirs are set"`
+	// SrcDirs labeled directories to ingest instead of SrcDir
+	SrcDirs []SrcDir `mapstructure:"src-dirs" description:"labeled directories to ingest instead of src-dir"`
+	// Include globs that files must match to be ingested, empty matches all
+	Include []string `mapstructure:"include" description:"globs that files must match to be ingested, empty matches all"`
+	// Exclude globs for files and directories that will not be ingested
+	Exclude []string `mapstructure:"exclude" description:"globs for files and directories that will not be ingested"`
+	// MinSize in bytes of files to ingest
+	MinSize int64 `mapstructure:"min-size" description:"minimum size in bytes of files to ingest"`
+	// MinAge since last modification of files to ingest
+	MinAge time.Duration `mapstructure:"min-age" description:"minimum time since the last modification of files to ingest"`
+	// MaxDepth to descend in to the directory tree, 0 is unlimited
+	MaxDepth int `mapstructure:"max-depth" description:"depth to descend in to the directory tree, 0 is unlimited"`
+	// FollowSymlinks to directories while walking
+	FollowSymlinks bool `mapstructure:"follow-symlinks" description:"follow symlinks to directories while walking"`
+	// SkipHidden files and directories (dotfiles)
+	SkipHidden bool `mapstructure:"skip-hidden" description:"skip hidden files and directories (dotfiles)"`
+	// Filesystem the directories are on, defaults to local
+	Filesystem filesystem.Config `mapstructure:"filesystem" description:"the filesystem the directories are on, defaults to local"`
+
+	fs  filesystem.FS
+	now func() time.Time
 }
 
-// Init noop.
-func (*FilePathInput) Init(context.Context) error {
+// Init validates the configured roots and globs.
+func (p *FilePathInput) Init(context.Context) error {
+	for i := range p.SrcDirs {
+		if p.SrcDirs[i].Path == "" {
+			return errors.Errorf("path_input: src-dirs[%d] has no path", i)
+		}
+		if p.SrcDirs[i].Label == "" {
+			p.SrcDirs[i].Label = p.SrcDirs[i].Path
+		}
+	}
+	for _, pattern := range append(append([]string{}, p.Include...), p.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "path_input: bad glob %s", pattern)
+		}
+	}
+	if p.now == nil {
+		p.now = time.Now
+	}
+	if p.fs == nil {
+		fs, err := filesystem.New(p.Filesystem)
+		if err != nil {
+			return errors.Wrap(err, "path_input")
+		}
+		p.fs = fs
+	}
 	return nil
 }
 
-// Consume runs the directory ingestion and pushes the contents of the
-// directory tree in to the pipeline.
-func (p *FilePathInput) Consume(sink chan<- types.Item) {
-	log.Tracef("started path_input at %s", p.SrcDir)
-	count := 0
-	if err := filepath.Walk(p.SrcDir, func(path string, info os.FileInfo, err error) error {
-		// skip root
-		if path == p.SrcDir {
-			return nil
+// matchAny tests if the path relative to the root or the file name matches
+// any of the globs.
+func matchAny(patterns []string, rel string) bool {
+	base := filepath.Base(rel)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDir tests if the walk should not descend in to the directory.
+func (p *FilePathInput) skipDir(rel string) bool {
+	if p.SkipHidden && strings.HasPrefix(filepath.Base(rel), ".") {
+		log.Debugf("path_input: skipping hidden dir %s", rel)
+		return true
+	}
+	if matchAny(p.Exclude, rel) {
+		log.Debugf("path_input: skipping excluded dir %s", rel)
+		return true
+	}
+	return false
+}
+
+// skipFile tests if the file should not be ingested.
+func (p *FilePathInput) skipFile(rel string, info os.FileInfo) bool {
+	if p.SkipHidden && strings.HasPrefix(info.Name(), ".") {
+		log.Debugf("path_input: skipping hidden file %s", rel)
+		return true
+	}
+	if matchAny(p.Exclude, rel) {
+		log.Debugf("path_input: skipping excluded file %s", rel)
+		return true
+	}
+	if len(p.Include) > 0 && !matchAny(p.Include, rel) {
+		log.Debugf("path_input: skipping file %s not matching includes", rel)
+		return true
+	}
+	if info.Size() < p.MinSize {
+		log.Debugf("path_input: skipping file %s smaller than %d bytes", rel, p.MinSize)
+		return true
+	}
+	if p.MinAge > 0 && p.now().Sub(info.ModTime()) < p.MinAge {
+		log.Debugf("path_input: skipping file %s modified less than %s ago", rel, p.MinAge)
+		return true
+	}
+	return false
+}
+
+// Validate implements the Validator interface on the FilePathInput, checking
+// that the local directories to ingest exist.
+func (p *FilePathInput) Validate() error {
+	if p.Filesystem.Type != "" && p.Filesystem.Type != filesystem.Local {
+		return nil
+	}
+	for _, root := range p.roots() {
+		info, err := os.Stat(root.Path)
+		if err != nil {
+			return errors.Wrap(err, "path_input")
+		}
+		if !info.IsDir() {
+			return errors.Errorf("path_input: %s is not a directory", root.Path)
 		}
+	}
+	return nil
+}
+
+// roots returns all of the directories to ingest: the SrcDirs if there are
+// any, so that the default SrcDir isn't ingested along with them, or else the
+// SrcDir.
+func (p *FilePathInput) roots() []SrcDir {
+	if len(p.SrcDirs) > 0 {
+		return p.SrcDirs
+	}
+	if p.SrcDir == "" {
+		return []SrcDir{}
+	}
+	return []SrcDir{{Path: p.SrcDir, Label: p.SrcDir}}
+}
+
+// walk recursively descends the directory tree at dir, calling fn for each
+// entry that passes the filters. ancestors contains the resolved paths of the
+// directories above dir so that symlink loops are not followed.
+func (p *FilePathInput) walk(root, dir string, depth int, ancestors map[string]bool, fn func(string, os.FileInfo)) error {
+	if p.MaxDepth > 0 && depth > p.MaxDepth {
+		return nil
+	}
+	infos, err := p.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		path := filepath.Join(dir, info.Name())
 		log.Debugf("path_input: encountered %s", path)
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 && p.FollowSymlinks {
+			if info, err = p.fs.Stat(path); err != nil {
+				log.Errorf("path_input: broken symlink %s: %s", path, err)
+				continue
+			}
+		}
+		if !info.IsDir() {
+			if !p.skipFile(rel, info) {
+				fn(path, info)
+			}
+			continue
+		}
+		if p.skipDir(rel) {
+			continue
+		}
+		real, err := p.fs.RealPath(path)
 		if err != nil {
-			log.Error(err)
 			return err
 		}
+		if ancestors[real] {
+			log.Warnf("path_input: skipping symlink loop at %s", path)
+			continue
+		}
+		fn(path, info)
+		ancestors[real] = true
+		err = p.walk(root, path, depth+1, ancestors, fn)
+		delete(ancestors, real)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Consume runs the directory ingestion and pushes the contents of the
+// directory trees in to the pipeline.
+func (p *FilePathInput) Consume(sink chan<- types.Item) {
+	for _, root := range p.roots() {
+		p.consume(root, sink)
+	}
+	if err := p.fs.Close(); err != nil {
+		log.Errorf("path_input: error closing filesystem: %s", err)
+	}
+}
+
+// consume ingests a single root directory.
+func (p *FilePathInput) consume(root SrcDir, sink chan<- types.Item) {
+	log.Tracef("started path_input at %s", root.Path)
+	count := 0
+	ancestors := map[string]bool{}
+	if real, err := p.fs.RealPath(root.Path); err == nil {
+		ancestors[real] = true
+	}
+	if err := p.walk(root.Path, root.Path, 1, ancestors, func(path string, info os.FileInfo) {
+		path = p.fs.URL(path)
 		log.Infof("path_input: found file: %s", path)
 		i := types.Item{
 			Identifiers: make(map[string]string),
+			ModTime:     info.ModTime(),
+			Source:      root.Label,
 			SourcePath:  path,
 			FileType:    types.File,
 		}
 		if info.IsDir() {
 			i.FileType = types.Directory
+		} else {
+			i.Size = info.Size()
 		}
 		sink <- i
 		count++
-		return nil
 	}); err != nil {
 		log.Errorf("path_input: %s", err)
 	}
-	log.Debugf("path_input: ingested %d files", count)
+	log.Debugf("path_input: ingested %d files from %s", count, root.Path)
 }
 
 func init() {
 	Register("filepath", func() Input {
 		return &FilePathInput{
-			SrcDir: "/src",
+			SrcDir:  "/src",
+			SrcDirs: []SrcDir{},
+			Include: []string{},
+			Exclude: []string{},
 		}
 	})
 }
@@ -7,27 +7,190 @@ file, You can obtain one at https://mozilla.org/MPL/2.0/.
 */
 package input
 
-// import (
-// 	"fmt"
-// 	"path/filepath"
-// 	"testing"
-
-// 	"github.com/rbtr/pachinko/internal/config"
-// )
-
-// func Test_walkDir(t *testing.T) {
-// 	f, _ := filepath.Abs("testdata")
-// 	input, _ := NewPathInput(config.Config{
-// 		SrcDir: f,
-// 	})
-// 	files := []string{}
-// 	for out := range input.Consume() {
-// 		files = append(files, out.SourcePath)
-// 	}
-// 	if len(files) != 5 {
-// 		t.Errorf("expected %d files, got %d", 5, len(files))
-// 	}
-// 	for _, t := range files {
-// 		fmt.Println(t)
-// 	}
-// }
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+func consumeAll(t *testing.T, p *FilePathInput) []string {
+	if err := p.Init(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	sink := make(chan types.Item)
+	go func() {
+		p.Consume(sink)
+		close(sink)
+	}()
+	files := []string{}
+	for i := range sink {
+		rel, _ := filepath.Rel(p.SrcDir, i.SourcePath)
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func Test_walkDir(t *testing.T) {
+	f, _ := filepath.Abs("testdata")
+	got := consumeAll(t, &FilePathInput{SrcDir: f})
+	want := []string{"a", "a/a.txt", "b", "b/b", "b/b.txt", "b/b/b.txt", "b/c", "b/c/c.txt", "b/c/d", "b/c/d/d.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func writeTree(t *testing.T, files map[string]int) string {
+	dir, err := ioutil.TempDir("", "path_input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFilePathInput_filters(t *testing.T) {
+	dir := writeTree(t, map[string]int{
+		".hidden/a.mkv":       10,
+		"show/.b.mkv":         10,
+		"show/c.mkv":          10,
+		"show/c.mkv.part":     10,
+		"show/d.nfo":          1,
+		"show/sub/deep/e.mkv": 10,
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		input FilePathInput
+		want  []string
+	}{
+		{
+			"no filters",
+			FilePathInput{},
+			[]string{".hidden", ".hidden/a.mkv", "show", "show/.b.mkv", "show/c.mkv", "show/c.mkv.part", "show/d.nfo", "show/sub", "show/sub/deep", "show/sub/deep/e.mkv"},
+		},
+		{
+			"skip hidden",
+			FilePathInput{SkipHidden: true},
+			[]string{"show", "show/c.mkv", "show/c.mkv.part", "show/d.nfo", "show/sub", "show/sub/deep", "show/sub/deep/e.mkv"},
+		},
+		{
+			"include and exclude",
+			FilePathInput{SkipHidden: true, Include: []string{"*.mkv", "*.part"}, Exclude: []string{"*.part", "show/sub"}},
+			[]string{"show", "show/c.mkv"},
+		},
+		{
+			"min size",
+			FilePathInput{SkipHidden: true, MinSize: 5},
+			[]string{"show", "show/c.mkv", "show/c.mkv.part", "show/sub", "show/sub/deep", "show/sub/deep/e.mkv"},
+		},
+		{
+			"max depth",
+			FilePathInput{SkipHidden: true, MaxDepth: 2},
+			[]string{"show", "show/c.mkv", "show/c.mkv.part", "show/d.nfo", "show/sub"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.SrcDir = dir
+			got := consumeAll(t, &tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilePathInput_minAge(t *testing.T) {
+	dir := writeTree(t, map[string]int{
+		"old.mkv": 1,
+		"new.mkv": 1,
+	})
+	defer os.RemoveAll(dir)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old.mkv"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	got := consumeAll(t, &FilePathInput{SrcDir: dir, MinAge: time.Minute})
+	want := []string{"old.mkv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFilePathInput_followSymlinks(t *testing.T) {
+	dir := writeTree(t, map[string]int{
+		"real/a.mkv": 1,
+	})
+	defer os.RemoveAll(dir)
+	if err := os.Symlink(filepath.Join(dir, "real"), filepath.Join(dir, "link")); err != nil {
+		t.Skip(err)
+	}
+	// a link back up the tree must not loop forever
+	if err := os.Symlink(dir, filepath.Join(dir, "real", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := consumeAll(t, &FilePathInput{SrcDir: dir})
+	want := []string{"link", "real", "real/a.mkv", "real/loop"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = consumeAll(t, &FilePathInput{SrcDir: dir, FollowSymlinks: true})
+	want = []string{"link", "link/a.mkv", "real", "real/a.mkv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFilePathInput_srcDirs(t *testing.T) {
+	tv := writeTree(t, map[string]int{"a.mkv": 1})
+	defer os.RemoveAll(tv)
+	movies := writeTree(t, map[string]int{"b.mkv": 1})
+	defer os.RemoveAll(movies)
+
+	// the default src-dir isn't ingested when only src-dirs are configured
+	p := Registry["filepath"]().(*FilePathInput)
+	p.SrcDir = filepath.Join(tv, "does-not-exist")
+	p.SrcDirs = []SrcDir{
+		{Path: tv, Label: "tv"},
+		{Path: movies},
+	}
+	if err := p.Init(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sink := make(chan types.Item)
+	go func() {
+		p.Consume(sink)
+		close(sink)
+	}()
+	got := map[string]string{}
+	for i := range sink {
+		got[filepath.Base(i.SourcePath)] = i.Source
+	}
+	want := map[string]string{"a.mkv": "tv", "b.mkv": movies}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rbtr/pachinko/internal/s3"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// S3Input lists the objects in an S3-compatible bucket under [prefix],
+// pushing them in to the pipeline with their s3://bucket/key urls as the
+// SourcePath.
+type S3Input struct {
+	s3.Config `mapstructure:",squash"`
+	// Prefix of the keys to ingest
+	Prefix string `mapstructure:"prefix" description:"prefix of the keys to ingest"`
+	// Label to tag the items with, defaults to s3://[bucket]/[prefix]
+	Label string `mapstructure:"label" description:"label to tag the items with, defaults to s3://[bucket]/[prefix]"`
+
+	client *s3.Client
+	ctx    context.Context
+}
+
+// Init creates the S3 client.
+func (p *S3Input) Init(ctx context.Context) error {
+	var err error
+	if p.client, err = s3.NewClient(p.Config); err != nil {
+		return err
+	}
+	if p.Label == "" {
+		p.Label = "s3://" + p.Bucket + "/" + p.Prefix
+	}
+	p.ctx = ctx
+	return nil
+}
+
+// Consume lists the bucket and pushes the objects in to the pipeline.
+func (p *S3Input) Consume(sink chan<- types.Item) {
+	log.Tracef("started s3_input at %s", p.Label)
+	count := 0
+	if err := p.client.List(p.ctx, p.Prefix, func(o s3.Object) error {
+		// skip "directory" placeholder objects
+		if strings.HasSuffix(o.Key, "/") {
+			return nil
+		}
+		log.Infof("s3_input: found object: %s", o.Key)
+		sink <- types.Item{
+			FileType:    types.File,
+			Identifiers: map[string]string{"etag": o.ETag},
+			ModTime:     o.LastModified,
+			Size:        o.Size,
+			Source:      p.Label,
+			SourcePath:  s3.URL(p.Bucket, o.Key),
+		}
+		count++
+		return nil
+	}); err != nil {
+		log.Errorf("s3_input: %s", err)
+	}
+	log.Debugf("s3_input: ingested %d objects", count)
+}
+
+func init() {
+	Register("s3", func() Input {
+		return &S3Input{
+			Config: s3.Config{
+				Endpoint:  "https://s3.amazonaws.com",
+				Region:    "us-east-1",
+				PathStyle: false,
+			},
+		}
+	})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/rbtr/pachinko/internal/torrent"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// TorrentInput queries a torrent client for completed torrents, pushing
+// their files in to the pipeline.
+type TorrentInput struct {
+	torrent.Config `mapstructure:",squash"`
+	// Categories the torrents must have one of (qBittorrent categories or
+	// tags, Transmission labels), empty is all
+	Categories []string `mapstructure:"categories" description:"categories the torrents must have one of (qBittorrent categories or tags, Transmission labels), empty is all"`
+	// MinRatio the torrents must have been seeded to
+	MinRatio float64 `mapstructure:"min-ratio" description:"ratio the torrents must have been seeded to"`
+	// Seeded requires that the client has stopped seeding the torrents
+	// because their seeding goals are met
+	Seeded bool `mapstructure:"seeded" description:"require that the client has stopped seeding the torrents because their seeding goals are met"`
+	// RemotePath is replaced with LocalPath in the paths of the files, for
+	// when the client sees the downloads at a different path than pachinko
+	RemotePath string `mapstructure:"remote-path" description:"replaced with local-path in the paths of the files, for when the client sees the downloads at a different path than pachinko"`
+	LocalPath  string `mapstructure:"local-path" description:"replaces remote-path in the paths of the files"`
+	// Label to tag the items with, defaults to the url
+	Label string `mapstructure:"label" description:"label to tag the items with, defaults to the url"`
+
+	client torrent.Client
+	ctx    context.Context
+	typ    torrent.Type
+}
+
+// Init creates the torrent client.
+func (p *TorrentInput) Init(ctx context.Context) error {
+	var err error
+	if p.client == nil {
+		if p.client, err = torrent.New(p.typ, p.Config); err != nil {
+			return err
+		}
+	}
+	if p.Label == "" {
+		p.Label = p.URL
+	}
+	p.ctx = ctx
+	return nil
+}
+
+// match tests if the torrent is ready to be sorted.
+func (p *TorrentInput) match(t torrent.Torrent) bool {
+	switch {
+	case !t.Complete:
+		log.Debugf("%s_input: skipping incomplete torrent %s", p.typ, t.Name)
+	case p.Seeded && !t.Seeded:
+		log.Debugf("%s_input: skipping torrent %s that is still seeding", p.typ, t.Name)
+	case t.Ratio < p.MinRatio:
+		log.Debugf("%s_input: skipping torrent %s with ratio %.2f", p.typ, t.Name, t.Ratio)
+	case len(p.Categories) > 0 && !t.HasLabel(p.Categories...):
+		log.Debugf("%s_input: skipping torrent %s not in categories", p.typ, t.Name)
+	default:
+		return true
+	}
+	return false
+}
+
+// localPath maps the path of the file as the client sees it to the path
+// pachinko sees.
+func (p *TorrentInput) localPath(path string) string {
+	if p.RemotePath != "" && strings.HasPrefix(path, p.RemotePath) {
+		return filepath.Join(p.LocalPath, strings.TrimPrefix(path, p.RemotePath))
+	}
+	return path
+}
+
+// Consume lists the torrents and pushes the files of the matching ones in
+// to the pipeline.
+func (p *TorrentInput) Consume(sink chan<- types.Item) {
+	log.Tracef("started %s_input at %s", p.typ, p.URL)
+	torrents, err := p.client.Torrents(p.ctx)
+	if err != nil {
+		log.Errorf("%s_input: %s", p.typ, err)
+		return
+	}
+	count := 0
+	for _, t := range torrents {
+		if !p.match(t) {
+			continue
+		}
+		for _, f := range t.Files {
+			path := p.localPath(filepath.Join(t.Dir, f.Name))
+			log.Infof("%s_input: found file: %s", p.typ, path)
+			sink <- types.Item{
+				FileType:    types.File,
+				Identifiers: map[string]string{torrent.Identifier: t.Hash},
+				Size:        f.Size,
+				Source:      p.Label,
+				SourcePath:  path,
+			}
+			count++
+		}
+	}
+	log.Debugf("%s_input: ingested %d files", p.typ, count)
+}
+
+func init() {
+	for t, url := range map[torrent.Type]string{
+		torrent.QBittorrent:  "http://localhost:8080",
+		torrent.Transmission: "http://localhost:9091",
+	} {
+		t, url := t, url
+		Register(string(t), func() Input {
+			return &TorrentInput{
+				Config:     torrent.Config{URL: url},
+				Categories: []string{},
+				Seeded:     true,
+				typ:        t,
+			}
+		})
+	}
+}
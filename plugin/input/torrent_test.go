@@ -0,0 +1,65 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rbtr/pachinko/internal/torrent"
+	"github.com/rbtr/pachinko/types"
+)
+
+type fakeTorrents []torrent.Torrent
+
+func (f fakeTorrents) Torrents(context.Context) ([]torrent.Torrent, error) { return f, nil }
+func (f fakeTorrents) Relocate(context.Context, string, string) error      { return nil }
+func (f fakeTorrents) Remove(context.Context, string) error                { return nil }
+
+func TestTorrentInput(t *testing.T) {
+	client := fakeTorrents{
+		{Hash: "seeded", Labels: []string{"tv"}, Ratio: 2, Complete: true, Seeded: true, Dir: "/data/downloads", Files: []torrent.File{{Name: "a.mkv", Size: 1}}},
+		{Hash: "seeding", Labels: []string{"tv"}, Ratio: 2, Complete: true, Dir: "/data/downloads", Files: []torrent.File{{Name: "b.mkv"}}},
+		{Hash: "incomplete", Labels: []string{"tv"}, Ratio: 2, Seeded: true, Dir: "/data/downloads", Files: []torrent.File{{Name: "c.mkv"}}},
+		{Hash: "low-ratio", Labels: []string{"tv"}, Ratio: 0.5, Complete: true, Seeded: true, Dir: "/data/downloads", Files: []torrent.File{{Name: "d.mkv"}}},
+		{Hash: "other", Labels: []string{"music"}, Ratio: 2, Complete: true, Seeded: true, Dir: "/data/downloads", Files: []torrent.File{{Name: "e.flac"}}},
+	}
+	p := &TorrentInput{
+		Config:     torrent.Config{URL: "http://localhost:8080"},
+		Categories: []string{"tv"},
+		MinRatio:   1,
+		Seeded:     true,
+		RemotePath: "/data",
+		LocalPath:  "/src",
+		client:     client,
+		typ:        torrent.QBittorrent,
+	}
+	if err := p.Init(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	sink := make(chan types.Item)
+	go func() {
+		p.Consume(sink)
+		close(sink)
+	}()
+	got := []types.Item{}
+	for i := range sink {
+		got = append(got, i)
+	}
+	want := []types.Item{{
+		FileType:    types.File,
+		Identifiers: map[string]string{torrent.Identifier: "seeded"},
+		Size:        1,
+		Source:      "http://localhost:8080",
+		SourcePath:  "/src/downloads/a.mkv",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
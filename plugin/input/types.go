@@ -26,6 +26,20 @@ type Input interface {
 	Init(context.Context) error
 }
 
+// Waiter is implemented by Inputs that receive items over time, like
+// webhooks, so that the pipeline can be rerun as items arrive.
+type Waiter interface {
+	// Wait blocks until the Input has items to Consume.
+	Wait(context.Context) error
+}
+
+// Validator is implemented by Inputs that check their options when the config
+// is loaded, before they are initialized, so that mistakes like missing keys
+// are found without connecting to anything.
+type Validator interface {
+	Validate() error
+}
+
 var Registry map[string](func() Input) = map[string](func() Input){}
 
 func Register(name string, initializer func() Input) {
@@ -0,0 +1,269 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxWebhookBody bounds the size of a request.
+const maxWebhookBody = 1 << 20
+
+// webhookServer receives hints and queues them until they are consumed. It
+// outlives the WebhookInputs, which are created each time the pipeline is
+// run, so that requests are accepted between runs.
+type webhookServer struct {
+	sync.Mutex
+	path    string
+	token   string
+	roots   []string
+	pending []Hint
+	ready   chan struct{}
+}
+
+var (
+	webhooksMu sync.Mutex
+	webhooks   = map[string]*webhookServer{}
+)
+
+// authorized tests the request for the token as a bearer token or a token
+// query parameter, for clients that can only call a url.
+func (s *webhookServer) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return false
+	}
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// allowed tests that the path is in one of the allowed roots, so that
+// requests can't sort anything else.
+func (s *webhookServer) allowed(path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, root := range s.roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		log.Warnf("webhook_input: unauthorized request from %s", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	// only the types that are sent by clients, and not by html forms
+	if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != "application/json" && t != "text/plain" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	hints, err := ReadHints(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, h := range hints {
+		if !s.allowed(h.Path) {
+			log.Warnf("webhook_input: %s is not in the allowed roots", h.Path)
+			http.Error(w, fmt.Sprintf("%s is not in the allowed roots", h.Path), http.StatusForbidden)
+			return
+		}
+	}
+	s.Lock()
+	s.pending = append(s.pending, hints...)
+	s.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	log.Infof("webhook_input: queued %d paths", len(hints))
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "queued %d paths\n", len(hints))
+}
+
+// take returns and clears the pending hints.
+func (s *webhookServer) take() []Hint {
+	s.Lock()
+	defer s.Unlock()
+	hints := s.pending
+	s.pending = nil
+	return hints
+}
+
+// WebhookInput accepts paths POSTed to an http endpoint, like from a
+// download client when a download completes, and pushes them in to the
+// pipeline. Directories are walked like the filepath input does.
+//
+// It is meant for `pachinko serve`, which reruns the pipeline whenever the
+// webhook receives paths.
+type WebhookInput struct {
+	// Listen address of the http server
+	Listen string `mapstructure:"listen" description:"listen address of the http server"`
+	// Path of the endpoint
+	Path string `mapstructure:"path" description:"path of the endpoint"`
+	// Token that requests must have as a bearer token or token parameter
+	Token string `mapstructure:"token" description:"token that requests must have as a bearer token or token parameter, required"`
+	// AllowedRoots are the directories that the paths must be in
+	AllowedRoots []string `mapstructure:"allowed-roots" description:"the directories that the paths must be in, required"`
+	// Delay after the first request before the pipeline runs, to batch
+	// requests that come in together
+	Delay time.Duration `mapstructure:"delay" description:"delay after the first request before the pipeline runs, to batch requests that come in together"`
+	// Label to tag the items with
+	Label string `mapstructure:"label" description:"label to tag the items with"`
+
+	server *webhookServer
+	walker *FilePathInput
+}
+
+// Validate implements the Validator interface on the WebhookInput, checking
+// that requests must have a token and can only sort paths in the allowed
+// roots, because anything that can reach the webhook, like a web page in a
+// browser on the same host, can send it requests.
+func (p *WebhookInput) Validate() error {
+	if p.Token == "" {
+		return errors.New("webhook_input: token must be set")
+	}
+	if len(p.AllowedRoots) == 0 {
+		return errors.New("webhook_input: allowed-roots must be set")
+	}
+	for _, root := range p.AllowedRoots {
+		if !filepath.IsAbs(root) {
+			return errors.Errorf("webhook_input: allowed root %s is not an absolute path", root)
+		}
+	}
+	return nil
+}
+
+// Init starts the http server, or attaches to the one started by a previous
+// run of the pipeline.
+func (p *WebhookInput) Init(ctx context.Context) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	roots := []string{}
+	for _, root := range p.AllowedRoots {
+		roots = append(roots, filepath.Clean(root))
+	}
+	p.walker = &FilePathInput{
+		Exclude:    []string{"*.part", "*.!qB", "*.!ut", "*.crdownload"},
+		SkipHidden: true,
+	}
+	if err := p.walker.Init(ctx); err != nil {
+		return err
+	}
+	if p.server != nil {
+		return nil
+	}
+
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+	if s, ok := webhooks[p.Listen]; ok {
+		s.Lock()
+		s.path, s.token, s.roots = p.Path, p.Token, roots
+		s.Unlock()
+		p.server = s
+		return nil
+	}
+	l, err := net.Listen("tcp", p.Listen)
+	if err != nil {
+		return errors.Wrap(err, "webhook_input")
+	}
+	p.server = &webhookServer{path: p.Path, token: p.Token, roots: roots, ready: make(chan struct{}, 1)}
+	webhooks[p.Listen] = p.server
+	srv := &http.Server{Handler: p.server}
+	go func() {
+		if err := srv.Serve(l); err != http.ErrServerClosed {
+			log.Errorf("webhook_input: %s", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		webhooksMu.Lock()
+		delete(webhooks, p.Listen)
+		webhooksMu.Unlock()
+		srv.Close()
+	}()
+	log.Infof("webhook_input: listening on %s%s", l.Addr(), p.Path)
+	return nil
+}
+
+// Wait implements the Waiter interface.
+func (p *WebhookInput) Wait(ctx context.Context) error {
+	for {
+		p.server.Lock()
+		n := len(p.server.pending)
+		p.server.Unlock()
+		if n > 0 {
+			break
+		}
+		select {
+		case <-p.server.ready:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-time.After(p.Delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Consume pushes the pending paths in to the pipeline.
+func (p *WebhookInput) Consume(sink chan<- types.Item) {
+	log.Trace("started webhook_input")
+	count := 0
+	for _, hint := range p.server.take() {
+		count += hint.consume(p.walker, p.Label, sink)
+	}
+	log.Debugf("webhook_input: ingested %d files", count)
+}
+
+func init() {
+	Register("webhook", func() Input {
+		return &WebhookInput{
+			Listen:       "127.0.0.1:8585",
+			Path:         "/webhook",
+			AllowedRoots: []string{},
+			Delay:        5 * time.Second,
+			Label:        "webhook",
+		}
+	})
+}
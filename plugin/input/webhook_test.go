@@ -0,0 +1,117 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package input
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+func TestWebhookInput(t *testing.T) {
+	dir := writeTree(t, map[string]int{
+		"show.s01e01.mkv":       1,
+		"movie/movie.mkv":       1,
+		"movie/movie.mkv.part":  1,
+		"movie/.hidden/foo.txt": 1,
+	})
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &WebhookInput{Listen: "127.0.0.1:0", Path: "/webhook", Token: "secret", AllowedRoots: []string{dir}, Label: "webhook"}
+	if err := p.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(target, auth, body string) int {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		if strings.HasPrefix(body, "{") {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		w := httptest.NewRecorder()
+		p.server.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := post("/webhook", "wrong", filepath.Join(dir, "show.s01e01.mkv")); code != http.StatusUnauthorized {
+		t.Errorf("got %d for a bad token", code)
+	}
+	if code := post("/webhook", "secret", `{"path": ""}`); code != http.StatusBadRequest {
+		t.Errorf("got %d for a missing path", code)
+	}
+	if code := post("/webhook", "secret", "/etc/passwd"); code != http.StatusForbidden {
+		t.Errorf("got %d for a path outside of the allowed roots", code)
+	}
+	if code := post("/webhook", "secret", filepath.Join(dir, "..", "other")); code != http.StatusForbidden {
+		t.Errorf("got %d for a path that escapes the allowed roots", code)
+	}
+	// a form, like a web page can send without a preflight
+	req := httptest.NewRequest(http.MethodPost, "/webhook?token=secret", strings.NewReader(filepath.Join(dir, "show.s01e01.mkv")))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	p.server.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("got %d for a form", w.Code)
+	}
+	if code := post("/webhook?token=secret", "", filepath.Join(dir, "show.s01e01.mkv")+"\n"); code != http.StatusAccepted {
+		t.Errorf("got %d for a path", code)
+	}
+	body := `{"path": "` + filepath.Join(dir, "movie") + `", "media-type": "movie", "tmdb": 603}`
+	if code := post("/webhook", "secret", body); code != http.StatusAccepted {
+		t.Errorf("got %d for a json hint", code)
+	}
+
+	if err := p.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sink := make(chan types.Item)
+	go func() {
+		p.Consume(sink)
+		close(sink)
+	}()
+	got := []string{}
+	for m := range sink {
+		rel, _ := filepath.Rel(dir, m.SourcePath)
+		got = append(got, rel+" "+string(m.MediaType)+" "+m.Identifiers["tmdb"])
+	}
+	sort.Strings(got)
+	want := []string{"movie/movie.mkv movie 603", "show.s01e01.mkv  "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWebhookInput_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     WebhookInput
+		valid bool
+	}{
+		{"valid", WebhookInput{Token: "secret", AllowedRoots: []string{"/src"}}, true},
+		{"no token", WebhookInput{AllowedRoots: []string{"/src"}}, false},
+		{"no roots", WebhookInput{Token: "secret"}, false},
+		{"relative root", WebhookInput{Token: "secret", AllowedRoots: []string{"src"}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.p.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: got %v, want valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
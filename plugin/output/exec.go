@@ -0,0 +1,231 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExecMode is when the exec output runs its command.
+type ExecMode string
+
+const (
+	// ExecItem runs the command for each item
+	ExecItem ExecMode = "item"
+	// ExecRun runs the command once, at the end of the datastream
+	ExecRun ExecMode = "run"
+)
+
+// ExecSummary is written to the stdin of the command in run mode.
+type ExecSummary struct {
+	DryRun  bool         `json:"dry-run"`
+	Started time.Time    `json:"started"`
+	Items   []types.Item `json:"items"`
+}
+
+// ExecOutput runs a command for each item, or once for the run, so that
+// existing scripts can be hooked in to a sort.
+//
+// In item mode the fields of the item are in the environment of the command,
+// as PACHINKO_SOURCE_PATH, PACHINKO_DESTINATION_PATH, etc, and the args are
+// templated with them, like {{.DestinationPath}}. In run mode the command is
+// sent an ExecSummary on its stdin, and the args are templated with it.
+//
+// It reports the items whose command failed as failed, so that failures fail
+// the run. The items it ran the command for keep the outcome they had from
+// the outputs it runs after, or are skipped, since the command doesn't move
+// or delete them itself.
+type ExecOutput struct {
+	// Command is the executable and its args, which are templates
+	Command []string `mapstructure:"command" description:"the executable and its args, which are templates"`
+	// Env are variables added to the environment of the command
+	Env map[string]string `mapstructure:"env" description:"variables added to the environment of the command"`
+	// Mode is item, to run the command for each item, or run, to run it
+	// once at the end of the datastream
+	Mode ExecMode `mapstructure:"mode" description:"run the command for each item, or once at the end of the datastream" enum:"item,run"`
+	// Timeout for the command to finish
+	Timeout time.Duration `mapstructure:"timeout" description:"time for the command to finish"`
+
+	ctx     context.Context
+	args    []*template.Template
+	dryRun  bool
+	started time.Time
+	results chan<- Result
+}
+
+func (o *ExecOutput) Init(ctx context.Context, cfg Config) error {
+	if len(o.Command) == 0 {
+		return errors.New("exec: command must be set")
+	}
+	switch o.Mode {
+	case ExecItem, ExecRun:
+	default:
+		return errors.Errorf("exec: unknown mode %s", o.Mode)
+	}
+	o.args = []*template.Template{}
+	for _, arg := range o.Command[1:] {
+		t, err := template.New("arg").Option("missingkey=zero").Parse(arg)
+		if err != nil {
+			return errors.Wrapf(err, "exec: error parsing arg %s", arg)
+		}
+		o.args = append(o.args, t)
+	}
+	o.ctx = ctx
+	o.dryRun = cfg.DryRun
+	o.started = time.Now()
+	return nil
+}
+
+// Report implements the Reporter interface on the ExecOutput.
+func (o *ExecOutput) Report(results chan<- Result) {
+	o.results = results
+}
+
+func (o *ExecOutput) report(m types.Item, err error) {
+	if o.results == nil {
+		return
+	}
+	outcome := Skipped
+	switch {
+	case err != nil:
+		outcome = Failed
+	case m.Outcome != "":
+		outcome = m.Outcome
+	}
+	o.results <- Result{Item: m, Outcome: outcome}
+}
+
+// env formats the fields of the item as environment variables.
+func env(m types.Item) []string {
+	e := newReportEntry(m, "")
+	vars := []string{}
+	for i, v := range e.row() {
+		if reportColumns[i] == "outcome" {
+			continue
+		}
+		vars = append(vars, envName(reportColumns[i])+"="+v)
+	}
+	for k, v := range m.Identifiers {
+		vars = append(vars, envName("identifier-"+k)+"="+v)
+	}
+	return vars
+}
+
+func envName(s string) string {
+	return "PACHINKO_" + strings.ToUpper(strings.Replace(s, "-", "_", -1))
+}
+
+// run runs the command with the args templated with the data.
+func (o *ExecOutput) run(data interface{}, env []string, stdin io.Reader) error {
+	args := make([]string, len(o.args))
+	for i, t := range o.args {
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			return errors.Wrapf(err, "error templating arg %s", o.Command[i+1])
+		}
+		args[i] = b.String()
+	}
+	if o.dryRun {
+		log.Infof("exec: dry run, not running %s %s", o.Command[0], strings.Join(args, " "))
+		return nil
+	}
+
+	ctx := o.ctx
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, o.Command[0], args...)
+	cmd.Env = os.Environ()
+	for k, v := range o.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Env = append(cmd.Env, env...)
+	cmd.Stdin = stdin
+	// the output goes to a file instead of a pipe, so that waiting for the
+	// command doesn't wait for any children it left running when it timed
+	// out
+	out, err := ioutil.TempFile("", "pachinko-exec")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+	cmd.Stdout = out
+	cmd.Stderr = out
+	log.Debugf("exec: running %s %s", o.Command[0], strings.Join(args, " "))
+	err = cmd.Run()
+	if _, serr := out.Seek(0, io.SeekStart); serr == nil {
+		s := bufio.NewScanner(out)
+		for s.Scan() {
+			log.Infof("exec: %s", s.Text())
+		}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("%s timed out after %s", o.Command[0], o.Timeout)
+	}
+	return errors.Wrap(err, o.Command[0])
+}
+
+// Receive implements the Plugin interface on the ExecOutput.
+func (o *ExecOutput) Receive(c <-chan types.Item) {
+	log.Trace("started exec output")
+	if o.Mode == ExecItem {
+		for m := range c {
+			err := o.run(newReportEntry(m, ""), env(m), nil)
+			if err != nil {
+				log.Errorf("exec: error running for %s: %s", m.SourcePath, err)
+			}
+			o.report(m, err)
+		}
+		return
+	}
+
+	summary := &ExecSummary{DryRun: o.dryRun, Started: o.started, Items: []types.Item{}}
+	for m := range c {
+		summary.Items = append(summary.Items, m)
+	}
+	if len(summary.Items) == 0 {
+		log.Debug("exec: no items, not running")
+		return
+	}
+	b, err := json.Marshal(summary)
+	if err == nil {
+		err = o.run(summary, nil, bytes.NewReader(b))
+	}
+	if err != nil {
+		log.Errorf("exec: error running for %d items: %s", len(summary.Items), err)
+	}
+	for _, m := range summary.Items {
+		o.report(m, err)
+	}
+}
+
+func init() {
+	Register("exec", func() Output {
+		return &ExecOutput{
+			Mode:    ExecItem,
+			Timeout: time.Minute,
+		}
+	})
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+func TestExecOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	episode := types.Item{
+		Identifiers:     map[string]string{"tvdb": "1"},
+		MediaType:       tv.TV,
+		SourcePath:      "/src/Show.S01E02.mkv",
+		DestinationPath: "/media/Show.S01E02.mkv",
+		// as it is when run after a mover
+		Outcome: Moved,
+	}
+	episode.TVMetadata.Name = "Show"
+	items := []types.Item{
+		episode,
+		{SourcePath: "/src/Show.nfo", Delete: true},
+		{SourcePath: "/src/fail"},
+		{SourcePath: "/src/sleep"},
+	}
+
+	run := func(o *ExecOutput, dryRun bool) map[string]string {
+		if err := o.Init(context.TODO(), Config{DryRun: dryRun}); err != nil {
+			t.Fatal(err)
+		}
+		results := make(chan Result, len(items))
+		o.Report(results)
+		c := make(chan types.Item, len(items))
+		for _, m := range items {
+			c <- m
+		}
+		close(c)
+		o.Receive(c)
+		close(results)
+		got := map[string]string{}
+		for r := range results {
+			got[r.Item.SourcePath] = r.Outcome
+		}
+		return got
+	}
+
+	t.Run("item", func(t *testing.T) {
+		o := &ExecOutput{
+			Command: []string{"sh", "-c", `echo "$1 $PACHINKO_TITLE $PACHINKO_IDENTIFIER_TVDB $FOO" >> "$0"; case "$1" in */fail) exit 1;; */sleep) sleep 5;; esac`, out, "{{.SourcePath}}"},
+			Env:     map[string]string{"FOO": "bar"},
+			Mode:    ExecItem,
+			Timeout: 500 * time.Millisecond,
+		}
+		got := run(o, false)
+		want := map[string]string{
+			"/src/Show.S01E02.mkv": Moved,
+			"/src/Show.nfo":        Skipped,
+			"/src/fail":            Failed,
+			"/src/sleep":           Failed,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got outcomes %v, want %v", got, want)
+		}
+		b, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantOut := "/src/Show.S01E02.mkv Show 1 bar\n/src/Show.nfo   bar\n/src/fail   bar\n/src/sleep   bar\n"
+		if string(b) != wantOut {
+			t.Errorf("got output %q, want %q", b, wantOut)
+		}
+	})
+
+	t.Run("run", func(t *testing.T) {
+		summary := filepath.Join(dir, "summary.json")
+		o := &ExecOutput{
+			Command: []string{"sh", "-c", `cat > "$0"; test "$1" = 4`, summary, "{{len .Items}}"},
+			Mode:    ExecRun,
+		}
+		got := run(o, false)
+		for _, m := range items {
+			if got[m.SourcePath] == Failed {
+				t.Errorf("%s failed", m.SourcePath)
+			}
+		}
+		b, err := ioutil.ReadFile(summary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s ExecSummary
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.Items) != len(items) || s.Items[0].TVMetadata.Name != "Show" {
+			t.Errorf("got summary %s", b)
+		}
+
+		o.Command = []string{"false"}
+		for path, outcome := range run(o, false) {
+			if outcome != Failed {
+				t.Errorf("%s: got %s, want %s", path, outcome, Failed)
+			}
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		o := &ExecOutput{Command: []string{"false"}, Mode: ExecItem}
+		for path, outcome := range run(o, true) {
+			if outcome == Failed {
+				t.Errorf("%s failed in a dry run", path)
+			}
+		}
+	})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"time"
+
+	"github.com/rbtr/pachinko/internal/external"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExternalOutput runs an executable as an output, sending it the items of
+// the datastream.
+type ExternalOutput struct {
+	external.Command `mapstructure:",squash"`
+
+	proc *external.Process
+}
+
+func (o *ExternalOutput) Init(ctx context.Context, cfg Config) error {
+	log.Trace("external_output: initializing")
+	var err error
+	o.proc, err = o.Start(ctx, "external_output", external.Output, cfg.DryRun)
+	return err
+}
+
+// Receive implements the Plugin interface on the ExternalOutput.
+func (o *ExternalOutput) Receive(c <-chan types.Item) {
+	log.Trace("started external_output")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.proc.Receive(func(m types.Item) {
+			log.Warnf("external_output: ignoring item %s sent by an output", m.SourcePath)
+		})
+	}()
+	var failed bool
+	for m := range c {
+		if failed {
+			continue
+		}
+		if err := o.proc.Send(m); err != nil {
+			log.Errorf("external_output: error sending %s, dropping the rest of the items: %s", m.SourcePath, err)
+			failed = true
+		}
+	}
+	if err := o.proc.CloseSend(); err != nil && !failed {
+		log.Errorf("external_output: %s", err)
+	}
+	<-done
+	if err := o.proc.Wait(); err != nil {
+		log.Error(err)
+	}
+}
+
+func init() {
+	Register("external", func() Output {
+		return &ExternalOutput{
+			Command: external.Command{
+				Timeout: 10 * time.Second,
+			},
+		}
+	})
+}
@@ -0,0 +1,296 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// LibraryRefresh is the config common to the media server library refresh
+// outputs. They collect the directories that items are moved in to and, at
+// the end of the datastream, ask the media server to scan each of them once.
+// They are Followers, so they run after the outputs that move the items.
+type LibraryRefresh struct {
+	// URL of the media server
+	URL string `mapstructure:"url" description:"url of the media server"`
+	// LocalPath is replaced with RemotePath in the directories, for when the
+	// media server sees the library at a different path than pachinko
+	LocalPath  string `mapstructure:"local-path" description:"replaced with remote-path in the directories, for when the media server sees the library at a different path than pachinko"`
+	RemotePath string `mapstructure:"remote-path" description:"replaces local-path in the directories"`
+
+	ctx    context.Context
+	dryRun bool
+	http   *http.Client
+	name   string
+	// refresh scans the directory, as the media server sees it
+	refresh func(dir string) error
+}
+
+func (l *LibraryRefresh) init(ctx context.Context, cfg Config, name string, refresh func(string) error) error {
+	if l.URL == "" {
+		return errors.Errorf("%s: url must be set", name)
+	}
+	l.URL = strings.TrimSuffix(l.URL, "/")
+	l.ctx = ctx
+	l.dryRun = cfg.DryRun
+	l.name = name
+	l.refresh = refresh
+	if l.http == nil {
+		l.http = http.DefaultClient
+	}
+	return nil
+}
+
+// remotePath maps the local path to the path the media server sees.
+func (l *LibraryRefresh) remotePath(path string) string {
+	if l.LocalPath != "" && strings.HasPrefix(path, l.LocalPath) {
+		return filepath.Join(l.RemotePath, strings.TrimPrefix(path, l.LocalPath))
+	}
+	return path
+}
+
+// do sends the request and checks the response status.
+func (l *LibraryRefresh) do(req *http.Request) ([]byte, error) {
+	res, err := l.http.Do(req.WithContext(l.ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.Errorf("%s %s: %s", req.Method, req.URL.Path, res.Status)
+	}
+	return body, nil
+}
+
+// Outcomes implements the Follower interface on the media server outputs.
+func (l *LibraryRefresh) Outcomes() []string {
+	return []string{Moved, Failed}
+}
+
+// Receive implements the Plugin interface on the media server outputs.
+func (l *LibraryRefresh) Receive(c <-chan types.Item) {
+	log.Tracef("started %s output", l.name)
+	items := []types.Item{}
+	for m := range c {
+		if m.Delete || m.DestinationPath == "" {
+			continue
+		}
+		items = append(items, m)
+	}
+	dirs := map[string][]types.Item{}
+	order := []string{}
+	for _, m := range reported(items) {
+		dir := filepath.Dir(m.DestinationPath)
+		if _, ok := dirs[dir]; !ok {
+			order = append(order, dir)
+		}
+		dirs[dir] = append(dirs[dir], m)
+	}
+	for _, dir := range order {
+		moved := 0
+		for _, m := range dirs[dir] {
+			if m.Outcome == Moved {
+				moved++
+			}
+		}
+		if moved == 0 {
+			log.Debugf("%s: no files were moved in to %s, not refreshing it", l.name, dir)
+			continue
+		}
+		if moved < len(dirs[dir]) {
+			log.Warnf("%s: not all files in %s were moved", l.name, dir)
+		}
+		remote := l.remotePath(dir)
+		if l.dryRun {
+			log.Infof("%s: (DRY_RUN) refresh %s", l.name, remote)
+			continue
+		}
+		if err := l.refresh(remote); err != nil {
+			log.Errorf("%s: error refreshing %s: %s", l.name, remote, err)
+			continue
+		}
+		log.Infof("%s: refreshed %s", l.name, remote)
+	}
+}
+
+// PlexRefresh refreshes the Plex library section that contains each
+// directory, scoped to the directory.
+type PlexRefresh struct {
+	LibraryRefresh `mapstructure:",squash"`
+	// Token is the X-Plex-Token
+	Token string `mapstructure:"token" description:"the X-Plex-Token"`
+}
+
+type plexSections struct {
+	Directories []struct {
+		Key       string `xml:"key,attr"`
+		Locations []struct {
+			Path string `xml:"path,attr"`
+		} `xml:"Location"`
+	} `xml:"Directory"`
+}
+
+func (p *PlexRefresh) Init(ctx context.Context, cfg Config) error {
+	return p.init(ctx, cfg, "plex", p.refreshDir)
+}
+
+func (p *PlexRefresh) get(path string, query url.Values) ([]byte, error) {
+	query.Set("X-Plex-Token", p.Token)
+	req, err := http.NewRequest(http.MethodGet, p.URL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/xml")
+	return p.do(req)
+}
+
+// section finds the key of the section with the longest location that
+// contains the directory.
+func (p *PlexRefresh) section(dir string) (string, error) {
+	body, err := p.get("/library/sections", url.Values{})
+	if err != nil {
+		return "", err
+	}
+	sections := plexSections{}
+	if err := xml.Unmarshal(body, &sections); err != nil {
+		return "", err
+	}
+	key, longest := "", -1
+	for _, d := range sections.Directories {
+		for _, l := range d.Locations {
+			loc := strings.TrimSuffix(l.Path, "/")
+			if (dir == loc || strings.HasPrefix(dir, loc+"/")) && len(loc) > longest {
+				key, longest = d.Key, len(loc)
+			}
+		}
+	}
+	if key == "" {
+		return "", errors.Errorf("no library section contains %s", dir)
+	}
+	return key, nil
+}
+
+func (p *PlexRefresh) refreshDir(dir string) error {
+	key, err := p.section(dir)
+	if err != nil {
+		return err
+	}
+	_, err = p.get("/library/sections/"+key+"/refresh", url.Values{"path": {dir}})
+	return err
+}
+
+// JellyfinRefresh notifies Jellyfin or Emby that each directory was
+// updated, which scans it in whichever library contains it.
+type JellyfinRefresh struct {
+	LibraryRefresh `mapstructure:",squash"`
+	// Token is an api key
+	Token string `mapstructure:"token" description:"the api key"`
+}
+
+func (j *JellyfinRefresh) Init(ctx context.Context, cfg Config) error {
+	return j.init(ctx, cfg, "jellyfin", j.refreshDir)
+}
+
+func (j *JellyfinRefresh) refreshDir(dir string) error {
+	b, err := json.Marshal(map[string]interface{}{
+		"Updates": []map[string]string{{"Path": dir, "UpdateType": "Created"}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, j.URL+"/Library/Media/Updated", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Emby-Token", j.Token)
+	_, err = j.do(req)
+	return err
+}
+
+// KodiRefresh scans each directory with Kodi's JSON-RPC VideoLibrary.Scan.
+type KodiRefresh struct {
+	LibraryRefresh `mapstructure:",squash"`
+	User           string `mapstructure:"user" description:"user to log in as"`
+	Password       string `mapstructure:"password" description:"password to log in with"`
+}
+
+func (k *KodiRefresh) Init(ctx context.Context, cfg Config) error {
+	return k.init(ctx, cfg, "kodi", k.refreshDir)
+}
+
+func (k *KodiRefresh) refreshDir(dir string) error {
+	b, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "VideoLibrary.Scan",
+		// kodi sources are directories with a trailing separator
+		"params": map[string]string{"directory": strings.TrimSuffix(dir, "/") + "/"},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, k.URL+"/jsonrpc", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if k.User != "" {
+		req.SetBasicAuth(k.User, k.Password)
+	}
+	body, err := k.do(req)
+	if err != nil {
+		return err
+	}
+	res := struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return errors.New(res.Error.Message)
+	}
+	return nil
+}
+
+func init() {
+	Register("plex", func() Output {
+		return &PlexRefresh{
+			LibraryRefresh: LibraryRefresh{URL: "http://localhost:32400"},
+		}
+	})
+	Register("jellyfin", func() Output {
+		return &JellyfinRefresh{
+			LibraryRefresh: LibraryRefresh{URL: "http://localhost:8096"},
+		}
+	})
+	Register("kodi", func() Output {
+		return &KodiRefresh{
+			LibraryRefresh: LibraryRefresh{URL: "http://localhost:8080"},
+		}
+	})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/filesystem"
+	"github.com/rbtr/pachinko/internal/library"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// LibraryIndex records the items that are moved in to the library, with
+// their identifiers, metadata, source, destination, size, and hash, in a
+// database that can be queried with "pachinko library". It is a Follower, so
+// it runs after the outputs that move the items.
+type LibraryIndex struct {
+	// Path of the database
+	Path string `mapstructure:"path" description:"path of the database"`
+
+	dryRun bool
+}
+
+func (l *LibraryIndex) Init(ctx context.Context, cfg Config) error {
+	if l.Path == "" {
+		return errors.New("library: path must be set")
+	}
+	l.dryRun = cfg.DryRun
+	return nil
+}
+
+// Outcomes implements the Follower interface on the LibraryIndex.
+func (l *LibraryIndex) Outcomes() []string {
+	return []string{Moved}
+}
+
+// Receive implements the Plugin interface on the LibraryIndex.
+func (l *LibraryIndex) Receive(c <-chan types.Item) {
+	log.Trace("started library output")
+	items := []types.Item{}
+	for m := range c {
+		items = append(items, m)
+	}
+	items = reported(items)
+	if l.dryRun {
+		for _, m := range items {
+			if m.Outcome == Moved {
+				log.Infof("library: dry-run: would record %s", m.DestinationPath)
+			}
+		}
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.Path), os.ModePerm); err != nil {
+		log.Errorf("library: %s", err)
+		return
+	}
+	db, err := library.Open(l.Path, false)
+	if err != nil {
+		log.Errorf("library: %s", err)
+		return
+	}
+	defer db.Close()
+	recorded := 0
+	for _, m := range items {
+		if m.Outcome != Moved || filesystem.IsURL(m.DestinationPath) {
+			continue
+		}
+		r := library.Record{Item: m, Sorted: time.Now()}
+		if m.FileType == types.File {
+			if r.Hash, err = library.Hash(m.DestinationPath); err != nil {
+				log.Warnf("library: error hashing %s: %s", m.DestinationPath, err)
+			}
+		}
+		if err := db.Put(r); err != nil {
+			log.Errorf("library: error recording %s: %s", m.DestinationPath, err)
+			continue
+		}
+		recorded++
+	}
+	log.Infof("library: recorded %d items in %s", recorded, l.Path)
+}
+
+func init() {
+	Register("library", func() Output {
+		return &LibraryIndex{
+			Path: library.DefaultPath,
+		}
+	})
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+// receiveMoved sends items that were reported moved in to the output.
+func receiveMoved(o Output, dir string, names ...string) {
+	c := make(chan types.Item)
+	go func() {
+		for _, name := range names {
+			c <- types.Item{
+				SourcePath:      filepath.Join("/src", name),
+				DestinationPath: filepath.Join(dir, name),
+				Outcome:         Moved,
+			}
+		}
+		close(c)
+	}()
+	o.Receive(c)
+}
+
+func TestLibraryRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	calls := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/library/sections":
+			if r.URL.Query().Get("X-Plex-Token") != "plex" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `<MediaContainer>
+<Directory key="1"><Location path="/library"/></Directory>
+<Directory key="2"><Location path="/library/tv"/></Directory>
+</MediaContainer>`)
+			return
+		case "/Library/Media/Updated":
+			if r.Header.Get("X-Emby-Token") != "jellyfin" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body := struct{ Updates []struct{ Path string } }{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			calls = append(calls, "jellyfin "+body.Updates[0].Path)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "/jsonrpc":
+			body := struct {
+				Method string
+				Params map[string]string
+			}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			calls = append(calls, "kodi "+body.Method+" "+body.Params["directory"])
+			fmt.Fprint(w, `{"id":1,"jsonrpc":"2.0","result":"OK"}`)
+			return
+		}
+		calls = append(calls, "plex "+r.URL.Path+" "+r.URL.Query().Get("path"))
+	}))
+	defer srv.Close()
+
+	refresh := LibraryRefresh{URL: srv.URL, LocalPath: dir, RemotePath: "/library"}
+	outputs := []Output{
+		&PlexRefresh{LibraryRefresh: refresh, Token: "plex"},
+		&JellyfinRefresh{LibraryRefresh: refresh, Token: "jellyfin"},
+		&KodiRefresh{LibraryRefresh: refresh},
+	}
+	for _, o := range outputs {
+		if err := o.Init(context.TODO(), Config{}); err != nil {
+			t.Fatal(err)
+		}
+		receiveMoved(o, dir, "tv/Show/Season 01/e01.mkv", "tv/Show/Season 01/e02.mkv", "movies/Movie (2000)/Movie.mkv")
+	}
+	want := []string{
+		"plex /library/sections/2/refresh /library/tv/Show/Season 01",
+		"plex /library/sections/1/refresh /library/movies/Movie (2000)",
+		"jellyfin /library/tv/Show/Season 01",
+		"jellyfin /library/movies/Movie (2000)",
+		"kodi VideoLibrary.Scan /library/tv/Show/Season 01/",
+		"kodi VideoLibrary.Scan /library/movies/Movie (2000)/",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got %q, want %q", calls, want)
+	}
+}
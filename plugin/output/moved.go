@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"github.com/rbtr/pachinko/types"
+)
+
+// Outcomes of items.
+const (
+	Moved   = "moved"
+	Deleted = "deleted"
+	Failed  = "failed"
+	Skipped = "skipped"
+)
+
+// AllOutcomes are all of the outcomes.
+var AllOutcomes = []string{Moved, Deleted, Failed, Skipped}
+
+// intended is what would happen to the item, for the items that weren't
+// reported.
+func intended(m types.Item) string {
+	switch {
+	case m.Delete:
+		return Deleted
+	case m.DestinationPath != "":
+		return Moved
+	}
+	return Skipped
+}
+
+// rank orders the outcomes that several outputs reported for an item, so
+// that the output that handled it wins over the ones that skipped it.
+var rank = map[string]int{Skipped: 1, Failed: 2, Moved: 3, Deleted: 3}
+
+// reported returns each of the items once, with its outcome set.
+//
+// Followers run after the Reporters, so the items have the outcome that was
+// reported for them, and an item reported by several Reporters, like a mover
+// that skipped it and one that moved it, has the outcome of the one that
+// handled it. Items that weren't reported, because the Follower doesn't run
+// after any Reporters, have the outcome that was intended for them.
+func reported(items []types.Item) []types.Item {
+	out := []types.Item{}
+	index := map[string]int{}
+	for _, m := range items {
+		i, seen := index[m.SourcePath]
+		switch {
+		case !seen:
+			index[m.SourcePath] = len(out)
+			out = append(out, m)
+		case rank[m.Outcome] > rank[out[i].Outcome]:
+			out[i] = m
+		}
+	}
+	for i := range out {
+		if out[i].Outcome == "" {
+			out[i].Outcome = intended(out[i])
+		}
+	}
+	return out
+}
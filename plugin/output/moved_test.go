@@ -0,0 +1,37 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+func TestOutcomes(t *testing.T) {
+	items := []types.Item{
+		// reported by a mover that skipped it and one that moved it
+		{SourcePath: "sftp://host/a.mkv", DestinationPath: "/dest/a.mkv", Outcome: Skipped},
+		{SourcePath: "sftp://host/a.mkv", DestinationPath: "/dest/a.mkv", Outcome: Moved},
+		{SourcePath: "sftp://host/b.mkv", DestinationPath: "/dest/b.mkv", Outcome: Failed},
+		// unreported
+		{SourcePath: "/src/c.mkv", DestinationPath: "/dest/c.mkv"},
+		{SourcePath: "/src/d.nfo", Delete: true},
+		{SourcePath: "/src/e.txt"},
+	}
+	got := reported(items)
+	want := []string{Moved, Failed, Moved, Deleted, Skipped}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Outcome != want[i] {
+			t.Errorf("%s: got outcome %s, want %s", got[i].SourcePath, got[i].Outcome, want[i])
+		}
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// NotifyFormat is the shape of the notification request.
+type NotifyFormat string
+
+const (
+	// NotifyJSON posts the template, or the notice data as JSON
+	NotifyJSON NotifyFormat = "json"
+	// NotifySlack posts {"text": [message]}
+	NotifySlack NotifyFormat = "slack"
+	// NotifyDiscord posts {"content": [message]}
+	NotifyDiscord NotifyFormat = "discord"
+	// NotifyMatrix sends an m.text event to the room send url
+	NotifyMatrix NotifyFormat = "matrix"
+	// NotifyNtfy posts the message as plain text to the topic url
+	NotifyNtfy NotifyFormat = "ntfy"
+)
+
+// NotifyMode is when notifications are sent.
+type NotifyMode string
+
+const (
+	// NotifySummary sends one notification at the end of the datastream
+	NotifySummary NotifyMode = "summary"
+	// NotifyItem sends a notification for each item
+	NotifyItem NotifyMode = "item"
+)
+
+const defaultNotifyTemplate = `{{if .DryRun}}(dry run) {{end}}pachinko: {{len .Moved}} moved, {{len .Deleted}} deleted, {{len .Failed}} failed
+{{range .Moved}}{{.Source}} -> {{.Destination}}
+{{end}}{{range .Failed}}failed: {{.Source}}{{with .Destination}} -> {{.}}{{end}}
+{{end}}`
+
+// Notice is an item and its outcome.
+type Notice struct {
+	Source      string
+	Destination string
+	Outcome     string
+	Item        types.Item
+}
+
+// NoticeData is the data the template is executed with.
+type NoticeData struct {
+	DryRun  bool
+	Notices []Notice
+	Moved   []Notice
+	Deleted []Notice
+	Failed  []Notice
+}
+
+// Notifier sends a notification about the items that were sorted to a
+// webhook or chat service, as a summary at the end of the datastream or for
+// each item.
+//
+// The message is rendered from a text/template executed with NoticeData.
+//
+// It is a Follower, so it runs after the outputs that move and delete the
+// items, and notifies the outcomes that they report.
+type Notifier struct {
+	// URL to send the notifications to
+	URL string `mapstructure:"url" description:"url to send the notifications to"`
+	// Format of the request: json, slack, discord, matrix, or ntfy
+	Format NotifyFormat `mapstructure:"format" description:"format of the request" enum:"json,slack,discord,matrix,ntfy"`
+	// Mode is summary or item
+	Mode NotifyMode `mapstructure:"mode" description:"send a summary of the run, or a notification for each item" enum:"summary,item"`
+	// Template of the message, or of the whole body for the json format
+	Template string `mapstructure:"template" description:"template of the message, or of the whole body for the json format"`
+	// Token to send as a bearer token
+	Token string `mapstructure:"token" description:"token to send as a bearer token"`
+	// Headers to add to the request
+	Headers map[string]string `mapstructure:"headers" description:"headers to add to the request"`
+	// Retries of failed requests
+	Retries int `mapstructure:"retries" description:"retries of failed requests"`
+	// RetryDelay between retries
+	RetryDelay time.Duration `mapstructure:"retry-delay" description:"delay between retries"`
+	// Skipped items are included, not only items that were moved, deleted,
+	// or failed
+	Skipped bool `mapstructure:"skipped" description:"include skipped items, not only items that were moved, deleted, or failed"`
+
+	ctx      context.Context
+	dryRun   bool
+	http     *http.Client
+	template *template.Template
+}
+
+func (n *Notifier) Init(ctx context.Context, cfg Config) error {
+	if n.URL == "" {
+		return errors.New("notify: url must be set")
+	}
+	switch n.Format {
+	case NotifyJSON, NotifySlack, NotifyDiscord, NotifyMatrix, NotifyNtfy:
+	default:
+		return errors.Errorf("notify: unknown format %s", n.Format)
+	}
+	switch n.Mode {
+	case NotifySummary, NotifyItem:
+	default:
+		return errors.Errorf("notify: unknown mode %s", n.Mode)
+	}
+	text := n.Template
+	if text == "" && n.Format != NotifyJSON {
+		text = defaultNotifyTemplate
+	}
+	if text != "" {
+		var err error
+		if n.template, err = template.New("notify").Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).Parse(text); err != nil {
+			return errors.Wrap(err, "notify: bad template")
+		}
+	}
+	if n.http == nil {
+		n.http = http.DefaultClient
+	}
+	n.ctx = ctx
+	n.dryRun = cfg.DryRun
+	return nil
+}
+
+// body renders the request body for the data.
+func (n *Notifier) body(data NoticeData) ([]byte, error) {
+	if n.template == nil {
+		return json.Marshal(data)
+	}
+	var buf bytes.Buffer
+	if err := n.template.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	message := buf.String()
+	switch n.Format {
+	case NotifySlack:
+		return json.Marshal(map[string]string{"text": message})
+	case NotifyDiscord:
+		return json.Marshal(map[string]string{"content": message})
+	case NotifyMatrix:
+		return json.Marshal(map[string]string{"msgtype": "m.text", "body": message})
+	}
+	return []byte(message), nil
+}
+
+// send sends the body, retrying on errors and server errors. Matrix events
+// are sent with the same transaction id on every attempt, so that the
+// server doesn't post a retried event twice.
+func (n *Notifier) send(body []byte) error {
+	method, url := http.MethodPost, n.URL
+	if n.Format == NotifyMatrix {
+		// matrix events are PUT with a unique transaction id
+		method = http.MethodPut
+		url = strings.TrimSuffix(url, "/") + "/" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	var err error
+	for attempt := 0; attempt <= n.Retries; attempt++ {
+		if attempt > 0 {
+			log.Debugf("notify: retrying in %s: %s", n.RetryDelay, err)
+			time.Sleep(n.RetryDelay)
+		}
+		var retry bool
+		if retry, err = n.sendOnce(method, url, body); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (n *Notifier) sendOnce(method, url string, body []byte) (bool, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(n.ctx)
+	if n.Format == NotifyNtfy {
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Title", "pachinko")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := n.http.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		retry := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return retry, errors.Errorf("%s: %s", res.Status, b)
+	}
+	return false, nil
+}
+
+// notify sends a notification about the notices.
+func (n *Notifier) notify(notices []Notice) {
+	data := NoticeData{DryRun: n.dryRun, Notices: notices}
+	for _, notice := range notices {
+		switch notice.Outcome {
+		case Moved:
+			data.Moved = append(data.Moved, notice)
+		case Deleted:
+			data.Deleted = append(data.Deleted, notice)
+		case Failed:
+			data.Failed = append(data.Failed, notice)
+		}
+	}
+	body, err := n.body(data)
+	if err != nil {
+		log.Errorf("notify: error rendering template: %s", err)
+		return
+	}
+	if err := n.send(body); err != nil {
+		log.Errorf("notify: error sending notification: %s", err)
+		return
+	}
+	log.Debugf("notify: sent notification for %d items", len(notices))
+}
+
+// Outcomes implements the Follower interface on the Notifier.
+func (n *Notifier) Outcomes() []string {
+	return AllOutcomes
+}
+
+// Receive implements the Plugin interface on the Notifier.
+func (n *Notifier) Receive(c <-chan types.Item) {
+	log.Trace("started notify output")
+	items := []types.Item{}
+	for m := range c {
+		items = append(items, m)
+	}
+	notices := []Notice{}
+	for _, m := range reported(items) {
+		if m.Outcome == Skipped && !n.Skipped {
+			continue
+		}
+		notices = append(notices, Notice{Source: m.SourcePath, Destination: m.DestinationPath, Outcome: m.Outcome, Item: m})
+	}
+	if len(notices) == 0 {
+		log.Debug("notify: nothing to notify")
+		return
+	}
+	if n.Mode == NotifyItem {
+		for _, notice := range notices {
+			n.notify([]Notice{notice})
+		}
+		return
+	}
+	n.notify(notices)
+}
+
+func init() {
+	Register("notify", func() Output {
+		return &Notifier{
+			Format:     NotifyJSON,
+			Mode:       NotifySummary,
+			Headers:    map[string]string{},
+			Retries:    3,
+			RetryDelay: 5 * time.Second,
+		}
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+func TestNotifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "dest.mkv"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	bodies := []map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// fail the first request to test the retries
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	n := &Notifier{
+		URL:      srv.URL,
+		Format:   NotifySlack,
+		Mode:     NotifySummary,
+		Template: "{{len .Moved}} moved{{range .Failed}}, {{.Source}} failed{{end}}",
+		Retries:  1,
+	}
+	if err := n.Init(context.TODO(), Config{}); err != nil {
+		t.Fatal(err)
+	}
+	c := make(chan types.Item, 3)
+	c <- types.Item{SourcePath: filepath.Join(dir, "src.mkv"), DestinationPath: filepath.Join(dir, "dest.mkv"), Outcome: Moved}
+	c <- types.Item{SourcePath: filepath.Join(dir, "dest.mkv"), DestinationPath: filepath.Join(dir, "other.mkv"), Outcome: Failed}
+	c <- types.Item{SourcePath: filepath.Join(dir, "skipped.nfo"), Outcome: Skipped}
+	close(c)
+	n.Receive(c)
+
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+	want := "1 moved, " + filepath.Join(dir, "dest.mkv") + " failed"
+	if len(bodies) != 1 || bodies[0]["text"] != want {
+		t.Errorf("got %v, want text %q", bodies, want)
+	}
+}
+
+func TestNotifier_matrixRetry(t *testing.T) {
+	paths := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		// fail the first request to test that the retry has the same txn id
+		if len(paths) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	n := &Notifier{
+		URL:      srv.URL + "/_matrix/client/v3/rooms/room/send/m.room.message",
+		Format:   NotifyMatrix,
+		Mode:     NotifySummary,
+		Template: "{{len .Moved}} moved",
+		Retries:  1,
+	}
+	if err := n.Init(context.TODO(), Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.send([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != paths[1] {
+		t.Errorf("got paths %v, want the same txn id twice", paths)
+	}
+}
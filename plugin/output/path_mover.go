@@ -9,35 +9,60 @@ package output
 
 import (
 	"context"
-	"io"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/filesystem"
 	"github.com/rbtr/pachinko/types"
 	log "github.com/sirupsen/logrus"
 )
 
 // FilepathMover is a file mover, it will move files from src to dest with
 // some options like creating dirs or overwriting existing dests.
+//
+// The source and destination can be on remote filesystems over SFTP. Files
+// are renamed within a filesystem and copied between them. Items that are not
+// on the source filesystem are skipped.
 type FilepathMover struct {
-	CreateDirs bool `mapstructure:"create-dirs"`
-	Overwrite  bool `mapstructure:"overwrite"`
-
-	dryRun bool
+	CreateDirs bool `mapstructure:"create-dirs" description:"create the destination directories"`
+	Overwrite  bool `mapstructure:"overwrite" description:"overwrite files that are already at the destination"`
+	// Filesystem the items are moved from, defaults to local
+	Filesystem filesystem.Config `mapstructure:"filesystem" description:"the filesystem the items are moved from, defaults to local"`
+	// DestFilesystem the items are moved to, defaults to the source filesystem
+	DestFilesystem filesystem.Config `mapstructure:"dest-filesystem" description:"the filesystem the items are moved to, defaults to the source filesystem"`
+
+	dryRun  bool
+	src     filesystem.FS
+	dest    filesystem.FS
+	results chan<- Result
 }
 
 func (mv *FilepathMover) Init(ctx context.Context, cfg Config) error {
 	mv.dryRun = cfg.DryRun
+	var err error
+	if mv.src == nil {
+		if mv.src, err = filesystem.New(mv.Filesystem); err != nil {
+			return errors.Wrap(err, "move_output")
+		}
+	}
+	if mv.dest == nil {
+		if mv.DestFilesystem.Type == "" {
+			mv.dest = mv.src
+		} else if mv.dest, err = filesystem.New(mv.DestFilesystem); err != nil {
+			return errors.Wrap(err, "move_output")
+		}
+	}
 	return nil
 }
 
 func (mv *FilepathMover) mkdir(dir string) error {
 	if mv.dryRun {
-		log.Infof("move_output: (DRY_RUN) mkdir %s", dir)
+		log.Infof("move_output: (DRY_RUN) mkdir %s", mv.dest.URL(dir))
 		return nil
 	}
-	return os.MkdirAll(dir, os.ModePerm)
+	return mv.dest.MkdirAll(dir)
 }
 
 // rename attempts to rename the file:
@@ -46,11 +71,14 @@ func (mv *FilepathMover) mkdir(dir string) error {
 // if src and dest are on different volumes, it will error with a cross-device
 // link message.
 func (mv *FilepathMover) rename(src, dest string) error {
+	if !mv.src.Same(mv.dest) {
+		return errors.New("src and dest are on different filesystems")
+	}
 	if mv.dryRun {
-		log.Infof("move_output: (DRY_RUN) rename %s -> %s", src, dest)
+		log.Infof("move_output: (DRY_RUN) rename %s -> %s", mv.src.URL(src), mv.dest.URL(dest))
 		return nil
 	}
-	return os.Rename(src, dest)
+	return mv.src.Rename(src, dest)
 }
 
 // move copies the file from src to dest:
@@ -59,40 +87,25 @@ func (mv *FilepathMover) rename(src, dest string) error {
 // faster within the filesystem boundary.
 func (mv *FilepathMover) move(src, dest string) error {
 	if mv.dryRun {
-		log.Infof("move_output: (DRY_RUN) copy %s -> %s", src, dest)
+		log.Infof("move_output: (DRY_RUN) copy %s -> %s", mv.src.URL(src), mv.dest.URL(dest))
 		return nil
 	}
-
-	in, err := os.Open(src)
-	if err != nil {
-		return errors.Errorf("error opening src: %s", err)
+	if err := filesystem.Copy(mv.dest, dest, mv.src, src); err != nil {
+		return err
 	}
-	defer in.Close()
-
-	out, err := os.Create(dest)
-	if err != nil {
-		return errors.Errorf("error opening dest: %s", err)
-	}
-	defer out.Close()
-
-	if _, err = io.Copy(out, in); err != nil {
-		return errors.Errorf("error writing dest: %s", err)
-	}
-
-	err = os.Remove(src)
-	if err != nil {
+	if err := mv.src.Remove(src); err != nil {
 		return errors.Errorf("error removing src: %s", err)
 	}
 	return nil
 }
 
-func (mv *FilepathMover) moveMedia(m types.Item) error {
+func (mv *FilepathMover) moveMedia(src string, m types.Item) error {
 	if m.DestinationPath == "" {
 		return errors.New("move_output: no dest path")
 	}
 	dir, _ := filepath.Split(m.DestinationPath)
 	// check for dest directory, create if doesn't exist and allowed
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
+	if _, err := mv.dest.Stat(dir); os.IsNotExist(err) {
 		if !mv.CreateDirs {
 			return errors.Errorf("move_output: dest (%s) does not exist and will not be created", dir)
 		}
@@ -101,28 +114,88 @@ func (mv *FilepathMover) moveMedia(m types.Item) error {
 		}
 	}
 	// check for dest file
-	if _, err := os.Stat(m.DestinationPath); !os.IsNotExist(err) {
+	if _, err := mv.dest.Stat(m.DestinationPath); !os.IsNotExist(err) {
 		if !mv.Overwrite {
 			return errors.Errorf("move_output: file (%s) already exists and will not be overwritten", m.DestinationPath)
 		}
 	}
 	// move src to dest
-	if err := mv.rename(m.SourcePath, m.DestinationPath); err != nil {
+	if err := mv.rename(src, m.DestinationPath); err != nil {
 		// failed to rename - probably cross-device link so try to move
-		return mv.move(m.SourcePath, m.DestinationPath)
+		return mv.move(src, m.DestinationPath)
 	}
 	return nil
 }
 
+// Report implements the Reporter interface on the FilepathMover.
+func (mv *FilepathMover) Report(results chan<- Result) {
+	mv.results = results
+}
+
+// Mover implements the Mover interface on the FilepathMover.
+func (mv *FilepathMover) Mover() {}
+
+func (mv *FilepathMover) report(m types.Item, outcome string) {
+	if mv.results != nil {
+		mv.results <- Result{Item: m, Outcome: outcome}
+	}
+}
+
+// deleteAll removes the items from the source filesystem, longest path first
+// so that directories are emptied before they are removed.
+func (mv *FilepathMover) deleteAll(items []types.Item) {
+	sort.SliceStable(items, func(i, j int) bool { return len(items[i].SourcePath) > len(items[j].SourcePath) })
+	for _, m := range items {
+		log.Infof("move_output: deleting %s", m.SourcePath)
+		if mv.dryRun {
+			mv.report(m, Deleted)
+			continue
+		}
+		path, _ := mv.src.Path(m.SourcePath)
+		if err := mv.src.Remove(path); err != nil {
+			log.Errorf("move_output: %s", err)
+			mv.report(m, Failed)
+			continue
+		}
+		mv.report(m, Deleted)
+	}
+}
+
 // Receive implements the Plugin interface on the FilepathMover.
 func (mv *FilepathMover) Receive(c <-chan types.Item) {
 	log.Trace("started mover output")
+	// the internal deleter only handles local paths, so deletes on remote
+	// filesystems are done here
+	deletes := []types.Item{}
 	for m := range c {
 		log.Tracef("mover_output: received_input %#v", m)
-		if err := mv.moveMedia(m); err != nil {
-			log.Errorf("mover_output: %s", err)
-		} else {
-			log.Infof("move_output: moved %s -> %s", m.SourcePath, m.DestinationPath)
+		src, ok := mv.src.Path(m.SourcePath)
+		switch {
+		case !ok:
+			log.Debugf("move_output: %s is not on the source filesystem, skipping", m.SourcePath)
+			mv.report(m, Skipped)
+		case m.Delete && filesystem.IsURL(m.SourcePath):
+			deletes = append(deletes, m)
+		case m.Delete, m.DestinationPath == "":
+			log.Debugf("move_output: %s has no destination, skipping", m.SourcePath)
+			mv.report(m, Skipped)
+		default:
+			if err := mv.moveMedia(src, m); err != nil {
+				log.Errorf("mover_output: %s", err)
+				mv.report(m, Failed)
+			} else {
+				log.Infof("move_output: moved %s -> %s", m.SourcePath, mv.dest.URL(m.DestinationPath))
+				mv.report(m, Moved)
+			}
+		}
+	}
+	mv.deleteAll(deletes)
+	if err := mv.src.Close(); err != nil {
+		log.Errorf("move_output: %s", err)
+	}
+	if mv.dest != mv.src {
+		if err := mv.dest.Close(); err != nil {
+			log.Errorf("move_output: %s", err)
 		}
 	}
 }
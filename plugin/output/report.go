@@ -0,0 +1,241 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReportFormat is the file format of the report.
+type ReportFormat string
+
+const (
+	ReportJSON ReportFormat = "json"
+	ReportCSV  ReportFormat = "csv"
+	ReportHTML ReportFormat = "html"
+)
+
+// ReportEntry is an item in the report.
+type ReportEntry struct {
+	Source          string            `json:"source"`
+	SourcePath      string            `json:"source-path"`
+	Directory       bool              `json:"directory"`
+	Size            int64             `json:"size"`
+	Category        string            `json:"category"`
+	MediaType       string            `json:"media-type"`
+	Title           string            `json:"title"`
+	Year            int               `json:"year"`
+	Season          int               `json:"season"`
+	Episode         int               `json:"episode"`
+	EpisodeTitle    string            `json:"episode-title"`
+	Resolution      string            `json:"resolution"`
+	Identifiers     map[string]string `json:"identifiers"`
+	DestinationPath string            `json:"destination-path"`
+	Delete          bool              `json:"delete"`
+	Outcome         string            `json:"outcome"`
+}
+
+// Report is a run report.
+type Report struct {
+	DryRun  bool          `json:"dry-run"`
+	Started time.Time     `json:"started"`
+	Items   []ReportEntry `json:"items"`
+}
+
+var reportColumns = []string{
+	"source", "source-path", "directory", "size", "category", "media-type",
+	"title", "year", "season", "episode", "episode-title", "resolution",
+	"identifiers", "destination-path", "delete", "outcome",
+}
+
+func newReportEntry(m types.Item, outcome string) ReportEntry {
+	e := ReportEntry{
+		Source:          m.Source,
+		SourcePath:      m.SourcePath,
+		Directory:       m.FileType == types.Directory,
+		Size:            m.Size,
+		Category:        string(m.Category),
+		MediaType:       string(m.MediaType),
+		Identifiers:     m.Identifiers,
+		DestinationPath: m.DestinationPath,
+		Delete:          m.Delete,
+		Outcome:         outcome,
+	}
+	switch m.MediaType {
+	case tv.TV:
+		e.Title = m.TVMetadata.Name
+		e.Year = m.TVMetadata.ReleaseYear
+		e.Season = m.TVMetadata.Season.Number
+		e.Episode = m.TVMetadata.Episode.Number
+		e.EpisodeTitle = m.TVMetadata.Episode.Title
+	case movie.Movie:
+		e.Title = m.MovieMetadata.Title
+		e.Year = m.MovieMetadata.ReleaseYear
+	}
+	if r := m.VideoMetadata.Resolution; r.Width > 0 {
+		e.Resolution = r.String()
+	}
+	return e
+}
+
+// row formats the entry as a csv row.
+func (e *ReportEntry) row() []string {
+	ids := make([]string, 0, len(e.Identifiers))
+	for k, v := range e.Identifiers {
+		ids = append(ids, k+"="+v)
+	}
+	sort.Strings(ids)
+	return []string{
+		e.Source, e.SourcePath, strconv.FormatBool(e.Directory),
+		strconv.FormatInt(e.Size, 10), e.Category, e.MediaType, e.Title,
+		strconv.Itoa(e.Year), strconv.Itoa(e.Season), strconv.Itoa(e.Episode),
+		e.EpisodeTitle, e.Resolution, strings.Join(ids, ";"), e.DestinationPath,
+		strconv.FormatBool(e.Delete), e.Outcome,
+	}
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pachinko report {{.Started.Format "2006-01-02 15:04:05"}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #eee; position: sticky; top: 0; }
+tr.failed { background: #fdd; }
+tr.deleted { color: #888; }
+tr.skipped { color: #aaa; }
+</style>
+</head>
+<body>
+<h1>pachinko report{{if .DryRun}} (dry run){{end}}</h1>
+<p>{{.Started.Format "2006-01-02 15:04:05 MST"}}, {{len .Items}} items</p>
+<table>
+<tr><th>outcome</th><th>source</th><th>category</th><th>media type</th><th>title</th><th>year</th><th>season</th><th>episode</th><th>identifiers</th><th>destination</th></tr>
+{{range .Items}}<tr class="{{.Outcome}}"><td>{{.Outcome}}</td><td>{{.SourcePath}}</td><td>{{.Category}}</td><td>{{.MediaType}}</td><td>{{.Title}}{{with .EpisodeTitle}} - {{.}}{{end}}</td><td>{{if .Year}}{{.Year}}{{end}}</td><td>{{if .Season}}{{.Season}}{{end}}</td><td>{{if .Episode}}{{.Episode}}{{end}}</td><td>{{range $k, $v := .Identifiers}}{{$k}}={{$v}} {{end}}</td><td>{{if .Delete}}(delete){{else}}{{.DestinationPath}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// ReportOutput writes a report of every item in the datastream, with its
+// metadata, destination, and outcome, to a file per run. Run with dry-run to
+// review what a sort would do.
+//
+// It is a Follower, so it runs after the outputs that move and delete the
+// items, and reports the outcomes that they report.
+type ReportOutput struct {
+	// Path of the report, {time} is replaced with the start time of the run
+	Path string `mapstructure:"path" description:"path of the report, {time} is replaced with the start time of the run"`
+	// Format of the report, json, csv, or html, defaults to the extension
+	// of the path
+	Format ReportFormat `mapstructure:"format" description:"format of the report, defaults to the extension of the path" enum:",json,csv,html"`
+
+	dryRun  bool
+	started time.Time
+}
+
+func (r *ReportOutput) Init(ctx context.Context, cfg Config) error {
+	if r.Path == "" {
+		return errors.New("report: path must be set")
+	}
+	if r.Format == "" {
+		r.Format = ReportFormat(strings.TrimPrefix(filepath.Ext(r.Path), "."))
+	}
+	switch r.Format {
+	case ReportJSON, ReportCSV, ReportHTML:
+	default:
+		return errors.Errorf("report: unknown format %s", r.Format)
+	}
+	r.dryRun = cfg.DryRun
+	r.started = time.Now()
+	return nil
+}
+
+func (r *ReportOutput) write(w io.Writer, report *Report) error {
+	switch r.Format {
+	case ReportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(reportColumns); err != nil {
+			return err
+		}
+		for i := range report.Items {
+			if err := cw.Write(report.Items[i].row()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case ReportHTML:
+		return reportHTML.Execute(w, report)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// Outcomes implements the Follower interface on the ReportOutput.
+func (r *ReportOutput) Outcomes() []string {
+	return AllOutcomes
+}
+
+// Receive implements the Plugin interface on the ReportOutput.
+func (r *ReportOutput) Receive(c <-chan types.Item) {
+	log.Trace("started report output")
+	items := []types.Item{}
+	for m := range c {
+		items = append(items, m)
+	}
+	report := &Report{DryRun: r.dryRun, Started: r.started, Items: []ReportEntry{}}
+	for _, m := range reported(items) {
+		report.Items = append(report.Items, newReportEntry(m, m.Outcome))
+	}
+
+	path := strings.Replace(r.Path, "{time}", r.started.Format("20060102T150405"), -1)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		log.Errorf("report: %s", err)
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		log.Errorf("report: %s", err)
+		return
+	}
+	defer f.Close()
+	if err := r.write(f, report); err != nil {
+		log.Errorf("report: error writing %s: %s", path, err)
+		return
+	}
+	log.Infof("report: wrote %d items to %s", len(report.Items), path)
+}
+
+func init() {
+	Register("report", func() Output {
+		return &ReportOutput{
+			Path: "pachinko-report-{time}.json",
+		}
+	})
+}
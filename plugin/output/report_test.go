@@ -0,0 +1,96 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+func TestReportOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	episode := types.Item{
+		Category:        types.Video,
+		FileType:        types.File,
+		Identifiers:     map[string]string{"tvdb": "1"},
+		MediaType:       tv.TV,
+		SourcePath:      "/src/Show.S01E02.mkv",
+		DestinationPath: "/media/tv/Show/Season 01/Show - S01E02.mkv",
+	}
+	episode.TVMetadata.Name = "Show"
+	episode.TVMetadata.Season.Number = 1
+	episode.TVMetadata.Episode.Number = 2
+	items := []types.Item{episode, {SourcePath: "/src/Show.nfo", Delete: true, FileType: types.File}}
+
+	run := func(name string) []byte {
+		r := &ReportOutput{Path: filepath.Join(dir, name)}
+		if err := r.Init(context.TODO(), Config{DryRun: true}); err != nil {
+			t.Fatal(err)
+		}
+		c := make(chan types.Item, len(items))
+		for _, m := range items {
+			c <- m
+		}
+		close(c)
+		r.Receive(c)
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	report := Report{}
+	if err := json.Unmarshal(run("report.json"), &report); err != nil {
+		t.Fatal(err)
+	}
+	want := ReportEntry{
+		SourcePath:      episode.SourcePath,
+		Category:        "video",
+		MediaType:       "tv",
+		Title:           "Show",
+		Season:          1,
+		Episode:         2,
+		Identifiers:     map[string]string{"tvdb": "1"},
+		DestinationPath: episode.DestinationPath,
+		Outcome:         Moved,
+	}
+	if !report.DryRun || len(report.Items) != 2 || !reflect.DeepEqual(report.Items[0], want) {
+		t.Errorf("got %#v, want %#v", report, want)
+	}
+	if report.Items[1].Outcome != Deleted {
+		t.Errorf("got outcome %s, want %s", report.Items[1].Outcome, Deleted)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(string(run("report.csv")))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || !reflect.DeepEqual(rows[0], reportColumns) || rows[1][12] != "tvdb=1" {
+		t.Errorf("got %q", rows)
+	}
+
+	if html := string(run("report.html")); !strings.Contains(html, "Show - S01E02.mkv") || !strings.Contains(html, "(dry run)") {
+		t.Errorf("html report is missing items:\n%s", html)
+	}
+}
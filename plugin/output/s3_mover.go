@@ -0,0 +1,143 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/s3"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// S3Mover moves objects within S3-compatible storage by copying them to
+// their destination key on the server side and then deleting the source.
+// Items marked for delete are deleted from the bucket.
+//
+// It handles the items whose SourcePath is the s3://bucket/key url of an
+// object in its bucket, from an s3 input, and skips the rest. The
+// DestinationPath is the destination key.
+type S3Mover struct {
+	s3.Config `mapstructure:",squash"`
+	// DestBucket to move objects in to, defaults to the source bucket
+	DestBucket string `mapstructure:"dest-bucket" description:"bucket to move objects in to, defaults to the source bucket"`
+	Overwrite  bool   `mapstructure:"overwrite" description:"overwrite objects that are already at the destination"`
+
+	client  *s3.Client
+	ctx     context.Context
+	dryRun  bool
+	results chan<- Result
+}
+
+func (mv *S3Mover) Init(ctx context.Context, cfg Config) error {
+	var err error
+	if mv.client, err = s3.NewClient(mv.Config); err != nil {
+		return err
+	}
+	if mv.DestBucket == "" {
+		mv.DestBucket = mv.Bucket
+	}
+	mv.ctx = ctx
+	mv.dryRun = cfg.DryRun
+	return nil
+}
+
+func (mv *S3Mover) moveObject(key string, m types.Item) error {
+	// keys don't have a leading separator, but the path solvers may add one
+	dest := strings.TrimPrefix(m.DestinationPath, "/")
+	if dest == "" {
+		return errors.New("s3_mover: no dest key")
+	}
+	if !mv.Overwrite {
+		exists, err := mv.client.Exists(mv.ctx, mv.DestBucket, dest)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errors.Errorf("s3_mover: object (%s) already exists and will not be overwritten", dest)
+		}
+	}
+	if mv.dryRun {
+		log.Infof("s3_mover: (DRY_RUN) copy %s -> %s", m.SourcePath, s3.URL(mv.DestBucket, dest))
+		return nil
+	}
+	if err := mv.client.Copy(mv.ctx, key, m.Size, mv.DestBucket, dest); err != nil {
+		return err
+	}
+	return mv.client.Delete(mv.ctx, key)
+}
+
+func (mv *S3Mover) deleteObject(key string, m types.Item) error {
+	if mv.dryRun {
+		log.Infof("s3_mover: (DRY_RUN) delete %s", m.SourcePath)
+		return nil
+	}
+	return mv.client.Delete(mv.ctx, key)
+}
+
+// Report implements the Reporter interface on the S3Mover.
+func (mv *S3Mover) Report(results chan<- Result) {
+	mv.results = results
+}
+
+// Mover implements the Mover interface on the S3Mover.
+func (mv *S3Mover) Mover() {}
+
+func (mv *S3Mover) report(m types.Item, outcome string) {
+	if mv.results != nil {
+		mv.results <- Result{Item: m, Outcome: outcome}
+	}
+}
+
+// Receive implements the Plugin interface on the S3Mover.
+func (mv *S3Mover) Receive(c <-chan types.Item) {
+	log.Trace("started s3_mover output")
+	for m := range c {
+		log.Tracef("s3_mover: received_input %#v", m)
+		key, ok := s3.Key(mv.Bucket, m.SourcePath)
+		switch {
+		case !ok:
+			log.Debugf("s3_mover: %s is not in bucket %s, skipping", m.SourcePath, mv.Bucket)
+			mv.report(m, Skipped)
+		case m.Delete:
+			if err := mv.deleteObject(key, m); err != nil {
+				log.Errorf("s3_mover: %s", err)
+				mv.report(m, Failed)
+			} else {
+				log.Infof("s3_mover: deleted %s", m.SourcePath)
+				mv.report(m, Deleted)
+			}
+		case m.DestinationPath != "":
+			if err := mv.moveObject(key, m); err != nil {
+				log.Errorf("s3_mover: %s", err)
+				mv.report(m, Failed)
+			} else {
+				log.Infof("s3_mover: moved %s -> %s", m.SourcePath, m.DestinationPath)
+				mv.report(m, Moved)
+			}
+		default:
+			log.Debugf("s3_mover: %s has no destination, skipping", m.SourcePath)
+			mv.report(m, Skipped)
+		}
+	}
+}
+
+func init() {
+	Register("s3-mover", func() Output {
+		return &S3Mover{
+			Config: s3.Config{
+				Endpoint:  "https://s3.amazonaws.com",
+				Region:    "us-east-1",
+				PathStyle: false,
+			},
+			Overwrite: false,
+		}
+	})
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/torrent"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// TorrentAction is what is done with a torrent after its files are moved.
+type TorrentAction string
+
+const (
+	// Remove the torrent from the client, leaving its data
+	Remove TorrentAction = "remove"
+	// Relocate the torrent's remaining data to a new location
+	Relocate TorrentAction = "relocate"
+)
+
+// TorrentOutput removes or relocates torrents in the torrent client once all
+// of their files in the datastream have been moved to their destinations.
+//
+// The files are moved by another output, so it is a Follower, and runs after
+// the outputs that move the items. Torrents with files that failed to move
+// are left alone.
+type TorrentOutput struct {
+	torrent.Config `mapstructure:",squash"`
+	// Action to take on the torrents, remove or relocate
+	Action TorrentAction `mapstructure:"action" description:"action to take on the torrents" enum:"remove,relocate"`
+	// Location to relocate the torrents to, as the client sees it
+	Location string `mapstructure:"location" description:"location to relocate the torrents to, as the client sees it"`
+
+	client torrent.Client
+	ctx    context.Context
+	dryRun bool
+	typ    torrent.Type
+}
+
+func (o *TorrentOutput) Init(ctx context.Context, cfg Config) error {
+	switch o.Action {
+	case Remove:
+	case Relocate:
+		if o.Location == "" {
+			return errors.Errorf("%s_output: location must be set to relocate", o.typ)
+		}
+	default:
+		return errors.Errorf("%s_output: unknown action %s", o.typ, o.Action)
+	}
+	var err error
+	if o.client == nil {
+		if o.client, err = torrent.New(o.typ, o.Config); err != nil {
+			return err
+		}
+	}
+	o.ctx = ctx
+	o.dryRun = cfg.DryRun
+	return nil
+}
+
+func (o *TorrentOutput) act(hash string) error {
+	if o.dryRun {
+		log.Infof("%s_output: (DRY_RUN) %s torrent %s", o.typ, o.Action, hash)
+		return nil
+	}
+	if o.Action == Relocate {
+		return o.client.Relocate(o.ctx, hash, o.Location)
+	}
+	return o.client.Remove(o.ctx, hash)
+}
+
+// Outcomes implements the Follower interface on the TorrentOutput.
+func (o *TorrentOutput) Outcomes() []string {
+	return AllOutcomes
+}
+
+// Receive implements the Plugin interface on the TorrentOutput.
+func (o *TorrentOutput) Receive(c <-chan types.Item) {
+	log.Tracef("started %s_output", o.typ)
+	items := []types.Item{}
+	for m := range c {
+		if _, ok := m.Identifiers[torrent.Identifier]; !ok || m.Delete || m.DestinationPath == "" {
+			continue
+		}
+		items = append(items, m)
+	}
+	torrents := map[string][]types.Item{}
+	order := []string{}
+	for _, m := range reported(items) {
+		hash := m.Identifiers[torrent.Identifier]
+		if _, ok := torrents[hash]; !ok {
+			order = append(order, hash)
+		}
+		torrents[hash] = append(torrents[hash], m)
+	}
+	for _, hash := range order {
+		moved := true
+		for _, m := range torrents[hash] {
+			moved = moved && m.Outcome == Moved
+		}
+		if !moved {
+			log.Warnf("%s_output: not all files of torrent %s were moved, skipping", o.typ, hash)
+			continue
+		}
+		if err := o.act(hash); err != nil {
+			log.Errorf("%s_output: %s", o.typ, err)
+			continue
+		}
+		log.Infof("%s_output: %s torrent %s", o.typ, o.Action, hash)
+	}
+}
+
+func init() {
+	for t, url := range map[torrent.Type]string{
+		torrent.QBittorrent:  "http://localhost:8080",
+		torrent.Transmission: "http://localhost:9091",
+	} {
+		t, url := t, url
+		Register(string(t), func() Output {
+			return &TorrentOutput{
+				Config: torrent.Config{URL: url},
+				Action: Remove,
+				typ:    t,
+			}
+		})
+	}
+}
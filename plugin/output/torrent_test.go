@@ -0,0 +1,62 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package output
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rbtr/pachinko/internal/torrent"
+	"github.com/rbtr/pachinko/types"
+)
+
+type fakeTorrents struct {
+	removed []string
+}
+
+func (f *fakeTorrents) Torrents(context.Context) ([]torrent.Torrent, error) { return nil, nil }
+func (f *fakeTorrents) Relocate(context.Context, string, string) error      { return nil }
+func (f *fakeTorrents) Remove(_ context.Context, hash string) error {
+	f.removed = append(f.removed, hash)
+	return nil
+}
+
+func TestTorrentOutput(t *testing.T) {
+	client := &fakeTorrents{}
+	o := &TorrentOutput{Action: Remove, client: client}
+	if err := o.Init(context.TODO(), Config{}); err != nil {
+		t.Fatal(err)
+	}
+	c := make(chan types.Item)
+	go func() {
+		c <- types.Item{
+			Identifiers:     map[string]string{torrent.Identifier: "moved"},
+			SourcePath:      "/src/moved.mkv",
+			DestinationPath: "/dest/moved.mkv",
+			Outcome:         Moved,
+		}
+		c <- types.Item{
+			Identifiers:     map[string]string{torrent.Identifier: "unmoved"},
+			SourcePath:      "/src/unmoved.mkv",
+			DestinationPath: "/dest/unmoved.mkv",
+			Outcome:         Failed,
+		}
+		c <- types.Item{
+			Identifiers:     map[string]string{torrent.Identifier: "unmoved"},
+			SourcePath:      "/src/unmoved.nfo",
+			DestinationPath: "/dest/unmoved.nfo",
+			Outcome:         Moved,
+		}
+		close(c)
+	}()
+	o.Receive(c)
+	if want := []string{"moved"}; !reflect.DeepEqual(client.removed, want) {
+		t.Errorf("got %v, want %v", client.removed, want)
+	}
+}
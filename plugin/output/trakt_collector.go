@@ -9,9 +9,7 @@ package output
 
 import (
 	"context"
-	"strconv"
 
-	"github.com/rbtr/go-trakt"
 	internaltrakt "github.com/rbtr/pachinko/internal/trakt"
 	"github.com/rbtr/pachinko/types"
 	"github.com/rbtr/pachinko/types/metadata/movie"
@@ -21,97 +19,80 @@ import (
 
 var _ Output = (*TraktCollector)(nil)
 
+// TraktCollector adds the tv episodes and movies in the datastream to the
+// Trakt collection, with their resolution, audio, and hdr details, in
+// batches of [batch-size] items.
 type TraktCollector struct {
-	Authfile string `mapstructure:"authfile"`
+	Authfile  string `mapstructure:"authfile" description:"file with the trakt credentials, from pachinko trakt"`
+	BatchSize int    `mapstructure:"batch-size" description:"number of items to add to the collection in each request"`
 
 	client *internaltrakt.Trakt
+	ctx    context.Context
+	dryRun bool
 }
 
 // Init reads the authfile, creates a client, refreshes the
 // credentials, and writes them back to the authfile. Any failures
 // will return an error.
 func (t *TraktCollector) Init(ctx context.Context, cfg Config) error {
-	auth, err := internaltrakt.ReadAuthFile(t.Authfile)
-	if err != nil {
-		return err
-	}
-	if t.client, err = internaltrakt.NewTrakt(auth); err != nil {
-		return err
-	}
-	auth, err = t.client.Refresh(ctx)
-	if err != nil {
-		return err
-	}
-	return internaltrakt.WriteAuthFile(t.Authfile, auth)
-}
-
-func (t *TraktCollector) collectTV(m types.Item) error {
-	tvdbID, err := strconv.Atoi(m.Identifiers["tvdb"])
-	log.Debugf("trakt_collector: collecting by tvdb id: %d", tvdbID)
-	if err != nil {
+	var err error
+	if t.client, err = internaltrakt.Login(ctx, t.Authfile); err != nil {
 		return err
 	}
-	resp, err := t.client.Collection(context.TODO(), &trakt.CollectionBody{
-		Episodes: []trakt.Episode{
-			{
-				IDs: trakt.IDs{
-					TVDB: tvdbID,
-				},
-			},
-		},
-	})
-	if err != nil {
-		return err
+	if t.BatchSize <= 0 {
+		t.BatchSize = internaltrakt.DefaultBatchSize
 	}
-	log.Debugf("trakt_collector: added %d, updated %d, existing %d", resp.Added.Episodes, resp.Updated.Episodes, resp.Existing.Episodes)
+	t.ctx = ctx
+	t.dryRun = cfg.DryRun
 	return nil
 }
 
-func (t *TraktCollector) collectMovie(m types.Item) error {
-	tmdbID, err := strconv.Atoi(m.Identifiers["tmdb"])
-	log.Debugf("trakt_collector: collecting by tmdb id: %d", tmdbID)
-	if err != nil {
-		return err
+// collect sends the batch to trakt.
+func (t *TraktCollector) collect(batch *internaltrakt.CollectionBody) {
+	if t.dryRun {
+		log.Infof("trakt_collector: dry-run: would collect %d movies and %d episodes", len(batch.Movies), batch.Len()-len(batch.Movies))
+		return
 	}
-	resp, err := t.client.Collection(context.TODO(), &trakt.CollectionBody{
-		Movies: []trakt.Movie{
-			{
-				IDs: trakt.IDs{
-					TMDb: tmdbID,
-				},
-			},
-		},
-	})
+	resp, err := t.client.AddToCollection(t.ctx, batch)
 	if err != nil {
-		return err
+		log.Errorf("trakt_collector: %s", err)
+		return
+	}
+	log.Debugf("trakt_collector: added %d, updated %d, existing %d movies", resp.Added.Movies, resp.Updated.Movies, resp.Existing.Movies)
+	log.Debugf("trakt_collector: added %d, updated %d, existing %d episodes", resp.Added.Episodes, resp.Updated.Episodes, resp.Existing.Episodes)
+	if n := resp.NotFound.Len(); n > 0 {
+		log.Warnf("trakt_collector: %d items not found", n)
 	}
-	log.Debugf("trakt_collector: added %d, updated %d, existing %d", resp.Added.Movies, resp.Updated.Movies, resp.Existing.Movies)
-	return nil
 }
 
 func (t *TraktCollector) Receive(in <-chan types.Item) {
 	log.Trace("started trakt_collector output")
+	batch := &internaltrakt.CollectionBody{}
 	for m := range in {
 		log.Tracef("trakt_collector: received_input %#v", m)
-		if m.MediaType == tv.TV {
-			log.Infof("trakt_collector: collecting tv")
-			if err := t.collectTV(m); err != nil {
-				log.Error(err)
-			}
+		if m.Delete || (m.MediaType != tv.TV && m.MediaType != movie.Movie) {
+			continue
 		}
-		if m.MediaType == movie.Movie {
-			log.Infof("trakt_collector: collecting movie")
-			if err := t.collectMovie(m); err != nil {
-				log.Error(err)
-			}
+		log.Infof("trakt_collector: collecting %s %s", m.MediaType, m.SourcePath)
+		if !batch.Add(m) {
+			log.Errorf("trakt_collector: %s has no trakt, tvdb, tmdb, or imdb id", m.SourcePath)
+			continue
 		}
+		if batch.Len() >= t.BatchSize {
+			t.collect(batch)
+			batch = &internaltrakt.CollectionBody{}
+		}
+	}
+	if batch.Len() > 0 {
+		t.collect(batch)
 	}
 }
 
 func init() {
 	Register("trakt-collector", func() Output {
 		return &TraktCollector{
-			Authfile: internaltrakt.DefaultAuthfile,
+			Authfile:  internaltrakt.DefaultAuthfile,
+			BatchSize: internaltrakt.DefaultBatchSize,
 		}
 	})
 }
@@ -31,6 +31,47 @@ type Output interface {
 	Init(context.Context, Config) error
 }
 
+// Result is the outcome, one of Moved, Deleted, Failed, or Skipped, of an
+// output handling an item.
+type Result struct {
+	Item    types.Item
+	Outcome string
+}
+
+// Reporter is an Output that reports the outcome of each item it receives,
+// so that other outputs can run after it on the items it moved.
+type Reporter interface {
+	// Report sets the channel that the result of each item is sent to. It is
+	// called before Receive, and Receive sends exactly one result for each
+	// item before it returns.
+	Report(chan<- Result)
+}
+
+// Mover is a Reporter that moves or deletes the items itself, like the
+// path-mover, rather than acting on what other outputs did with them. The
+// Followers and the internal deleter run after the Movers by default.
+type Mover interface {
+	Reporter
+	// Mover marks the Reporter as a Mover
+	Mover()
+}
+
+// Follower is an Output that acts on the outcomes of the items, like a
+// notification or a library refresh. Unless it is configured to run after
+// other outputs, it runs after all of the Movers, and unless it is
+// configured with the outcomes to receive, it receives its Outcomes.
+type Follower interface {
+	// Outcomes are the outcomes of the items it receives by default
+	Outcomes() []string
+}
+
+// Validator is implemented by Outputs that check their options when the
+// config is loaded, before they are initialized, so that mistakes like
+// missing keys are found without connecting to anything.
+type Validator interface {
+	Validate() error
+}
+
 var Registry map[string](func() Output) = map[string](func() Output){}
 
 func Register(name string, initializer func() Output) {
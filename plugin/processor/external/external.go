@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package external
+
+import (
+	"context"
+	"time"
+
+	internalext "github.com/rbtr/pachinko/internal/external"
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// External runs an executable as a processor. The items of the datastream
+// are sent to it, and the items it sends back continue down the datastream,
+// so it can change, drop, or add items.
+type External struct {
+	internalext.Command `mapstructure:",squash"`
+
+	proc *internalext.Process
+}
+
+func (p *External) Init(ctx context.Context) error {
+	log.Trace("external_processor: initializing")
+	var err error
+	p.proc, err = p.Start(ctx, "external_processor", internalext.Processor, false)
+	return err
+}
+
+func (p *External) Process(in <-chan types.Item, out chan<- types.Item) {
+	log.Trace("started external_processor")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.proc.Receive(func(m types.Item) {
+			out <- m
+		})
+	}()
+	var failed bool
+	for m := range in {
+		if failed {
+			out <- m
+			continue
+		}
+		log.Tracef("external_processor: sending %#v", m)
+		if err := p.proc.Send(m); err != nil {
+			// pass the rest of the datastream through, so that it isn't
+			// lost if the executable exited
+			log.Errorf("external_processor: error sending %s, passing the rest of the items through unchanged: %s", m.SourcePath, err)
+			failed = true
+			out <- m
+		}
+	}
+	if err := p.proc.CloseSend(); err != nil && !failed {
+		log.Errorf("external_processor: %s", err)
+	}
+	<-done
+	if err := p.proc.Wait(); err != nil {
+		log.Error(err)
+	}
+}
+
+func init() {
+	for _, t := range processor.Types {
+		processor.Register(t, "external", func() processor.Processor {
+			return &External{
+				Command: internalext.Command{
+					Timeout: 10 * time.Second,
+				},
+			}
+		})
+	}
+}
@@ -22,11 +22,19 @@ import (
 
 // Client TODO.
 type TMDbClient struct {
-	APIKey string `mapstructure:"api-key"`
+	APIKey string `mapstructure:"api-key" description:"TMDb api key"`
 
 	client *api.Client
 }
 
+// Validate implements the Validator interface on the TMDbClient.
+func (c *TMDbClient) Validate() error {
+	if c.APIKey == "" {
+		return errors.New("tmdb: api-key must be set")
+	}
+	return nil
+}
+
 func (c *TMDbClient) Init(context.Context) error {
 	var err error
 	if c.client, err = api.Init(c.APIKey); err != nil {
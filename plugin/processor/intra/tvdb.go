@@ -30,13 +30,24 @@ var matcher *regexp.Regexp = regexp.MustCompile(`[^'\w]`)
 
 // TVDbClient adds metadata from the TVDb.
 type TVDbClient struct {
-	APIKey       string `mapstructure:"api-key"`
-	RequestLimit int64  `mapstructure:"request-limit"`
+	APIKey       string `mapstructure:"api-key" description:"TVDb api key"`
+	RequestLimit int64  `mapstructure:"request-limit" description:"requests per second to make to the TVDb"`
 
 	client  *api.Client
 	limiter *time.Ticker
 }
 
+// Validate implements the Validator interface on the TVDbClient.
+func (c *TVDbClient) Validate() error {
+	if c.APIKey == "" {
+		return errors.New("tvdb: api-key must be set")
+	}
+	if c.RequestLimit <= 0 {
+		return errors.New("tvdb: request-limit must be greater than 0")
+	}
+	return nil
+}
+
 func (c *TVDbClient) Init(context.Context) error {
 	authn := &models.Auth{
 		Apikey: c.APIKey,
@@ -102,6 +113,7 @@ func (c *TVDbClient) addTVDBMetadata(m types.Item) types.Item {
 	}
 	log.Debugf("tvdb_decorator: got episode from tvdb: %v", ep)
 	m.Identifiers["tvdb"] = strconv.FormatInt(ep.ID, 10)
+	m.Identifiers["tvdb-series"] = strconv.FormatInt(series.ID, 10)
 	m.TVMetadata.Name = series.SeriesName
 	m.TVMetadata.AbsoluteNumber = int(ep.AbsoluteNumber)
 	m.TVMetadata.Episode.Title = ep.EpisodeName
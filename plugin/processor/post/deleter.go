@@ -19,10 +19,10 @@ import (
 )
 
 type Deleter struct {
-	Categories     []string `mapstructure:"categories"`
-	Extensions     []string `mapstructure:"extensions"`
-	Directories    bool     `mapstructure:"directories"`
-	MatcherStrings []string `mapstructure:"matchers"`
+	Categories     []string `mapstructure:"categories" description:"not implemented"`
+	Extensions     []string `mapstructure:"extensions" description:"extensions of files to delete"`
+	Directories    bool     `mapstructure:"directories" description:"delete directories"`
+	MatcherStrings []string `mapstructure:"matchers" description:"regular expressions matched against the source path of files to delete"`
 
 	matchers []*regexp.Regexp
 }
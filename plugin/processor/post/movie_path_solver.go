@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"path"
 
+	"github.com/pkg/errors"
 	"github.com/rbtr/pachinko/plugin/processor"
 	"github.com/rbtr/pachinko/types"
 	"github.com/rbtr/pachinko/types/metadata/movie"
@@ -19,10 +20,23 @@ import (
 )
 
 type MoviePathSolver struct {
-	DestDir      string `mapstructure:"dest-dir"`
-	MovieDirs    bool   `mapstructure:"movie-dirs"`
-	MoviesPrefix string `mapstructure:"movie-prefix"`
-	OutputFormat string `mapstructure:"format"`
+	DestDir      string `mapstructure:"dest-dir" description:"the directory to sort in to"`
+	MovieDirs    bool   `mapstructure:"movie-dirs" description:"sort each movie in to its own directory"`
+	MoviesPrefix string `mapstructure:"movie-prefix" description:"the directory in the dest-dir to sort movies in to"`
+	OutputFormat string `mapstructure:"format" description:"not implemented"`
+	// DestDirMustExist is set when the movers don't create the directories
+	DestDirMustExist bool `mapstructure:"-"`
+}
+
+// Validate implements the Validator interface on the MoviePathSolver.
+func (p *MoviePathSolver) Validate() error {
+	if p.DestDir == "" {
+		return errors.New("movie_destination: dest-dir must be set")
+	}
+	if p.DestDirMustExist {
+		return errors.Wrap(checkDir(p.DestDir), "movie_destination")
+	}
+	return nil
 }
 
 func (*MoviePathSolver) Init(context.Context) error {
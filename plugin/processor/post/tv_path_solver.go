@@ -10,8 +10,10 @@ package post
 import (
 	"context"
 	"fmt"
+	"os"
 	"path"
 
+	"github.com/pkg/errors"
 	"github.com/rbtr/pachinko/plugin/processor"
 	"github.com/rbtr/pachinko/types"
 	"github.com/rbtr/pachinko/types/metadata/tv"
@@ -19,11 +21,37 @@ import (
 )
 
 type TVPathSolver struct {
-	DestDir      string `mapstructure:"dest-dir"`
-	EpisodeNames bool   `mapstructure:"episode-names"`
-	TVPrefix     string `mapstructure:"tv-prefix"`
-	SeasonDirs   bool   `mapstructure:"season-dirs"`
-	OutputFormat string `mapstructure:"format"`
+	DestDir      string `mapstructure:"dest-dir" description:"the directory to sort in to"`
+	EpisodeNames bool   `mapstructure:"episode-names" description:"add the titles of the episodes to the file names"`
+	TVPrefix     string `mapstructure:"tv-prefix" description:"the directory in the dest-dir to sort tv in to"`
+	SeasonDirs   bool   `mapstructure:"season-dirs" description:"sort episodes in to a directory for each season"`
+	OutputFormat string `mapstructure:"format" description:"not implemented"`
+	// DestDirMustExist is set when the movers don't create the directories
+	DestDirMustExist bool `mapstructure:"-"`
+}
+
+// Validate implements the Validator interface on the TVPathSolver.
+func (p *TVPathSolver) Validate() error {
+	if p.DestDir == "" {
+		return errors.New("tv_destination: dest-dir must be set")
+	}
+	if p.DestDirMustExist {
+		return errors.Wrap(checkDir(p.DestDir), "tv_destination")
+	}
+	return nil
+}
+
+// checkDir checks that the directory exists, so that a library that isn't
+// mounted is found before anything is moved.
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.Errorf("dest-dir %s is not a directory", dir)
+	}
+	return nil
 }
 
 func (*TVPathSolver) Init(context.Context) error {
@@ -0,0 +1,166 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package tvmeta
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/rbtr/pachinko/internal/archive"
+	internalpre "github.com/rbtr/pachinko/internal/plugin/processor/pre"
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Extractor unpacks archives in to [work-dir] and injects the extracted files
+// in to the pipeline as new items, categorized by the configured categorizer.
+// Archives (including every volume of a multi-volume rar set) are only marked
+// for deletion once they have been extracted successfully.
+type Extractor struct {
+	WorkDir string   `mapstructure:"work-dir" description:"directory to extract archives in to"`
+	Formats []string `mapstructure:"formats" description:"formats of the archives to extract" enum:"gz,rar,7z,tar,tgz,zip"`
+
+	categorizer processor.Categorizer
+	dryRun      bool
+	formats     map[archive.Format]bool
+}
+
+// UseCategorizer implements the CategorizerUser interface on the Extractor.
+func (p *Extractor) UseCategorizer(c processor.Categorizer) {
+	p.categorizer = c
+}
+
+// UseDryRun implements the DryRunUser interface on the Extractor.
+func (p *Extractor) UseDryRun(dryRun bool) {
+	p.dryRun = dryRun
+}
+
+func (p *Extractor) Init(ctx context.Context) error {
+	log.Trace("extractor: initializing")
+	p.formats = map[archive.Format]bool{}
+	for _, f := range p.Formats {
+		p.formats[archive.Format(f)] = true
+	}
+	if p.categorizer != nil {
+		return nil
+	}
+	categorizer := internalpre.NewCategorizer()
+	p.categorizer = categorizer
+	return categorizer.Init(ctx)
+}
+
+// extract unpacks the archive and returns the items for the extracted files
+// and the paths of the archive volumes that were read.
+func (p *Extractor) extract(m types.Item) ([]types.Item, []string, error) {
+	dest := filepath.Join(p.WorkDir, archive.Name(m.SourcePath))
+	log.Infof("extractor: extracting %s to %s", m.SourcePath, dest)
+	res, err := archive.Extract(m.SourcePath, dest)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := []types.Item{}
+	for _, f := range res.Files {
+		log.Debugf("extractor: extracted %s", f)
+		items = append(items, p.categorizer.Categorize(types.Item{
+			FileType:    types.File,
+			Identifiers: make(map[string]string),
+			Source:      m.Source,
+			SourcePath:  f,
+		}))
+	}
+	// the extraction dir is pushed so it can be cleaned up after it is emptied
+	items = append(items, types.Item{
+		FileType:    types.Directory,
+		Identifiers: make(map[string]string),
+		Source:      m.Source,
+		SourcePath:  dest,
+	})
+	return items, res.Volumes, nil
+}
+
+func (p *Extractor) Process(in <-chan types.Item, out chan<- types.Item) {
+	log.Trace("started extractor processor")
+	extracted := map[string]bool{}
+	continuations := []types.Item{}
+	for m := range in {
+		log.Tracef("extractor: received input: %#v", m)
+		format := archive.Detect(m.SourcePath)
+		if m.FileType == types.Directory || !p.formats[format] {
+			log.Debugf("extractor: %s is not an archive to extract, skipping", m.SourcePath)
+			out <- m
+			continue
+		}
+		if p.dryRun {
+			if format != archive.Rar || !archive.IsContinuation(m.SourcePath) {
+				log.Infof("extractor: dry run, not extracting %s to %s", m.SourcePath, filepath.Join(p.WorkDir, archive.Name(m.SourcePath)))
+			}
+			out <- m
+			continue
+		}
+		if format == archive.Rar && archive.IsContinuation(m.SourcePath) {
+			if extracted[filepath.Clean(m.SourcePath)] {
+				log.Debugf("extractor: %s was extracted", m.SourcePath)
+				m.Delete = true
+				out <- m
+				continue
+			}
+			// held until the first volume has been extracted
+			continuations = append(continuations, m)
+			continue
+		}
+		items, volumes, err := p.extract(m)
+		if err != nil {
+			log.Errorf("extractor: error extracting %s: %s", m.SourcePath, err)
+			out <- m
+			continue
+		}
+		for _, v := range volumes {
+			extracted[filepath.Clean(v)] = true
+		}
+		m.Delete = true
+		out <- m
+		for _, i := range items {
+			out <- i
+		}
+		// release the continuations that were extracted with this volume
+		held := continuations[:0]
+		for _, c := range continuations {
+			if !extracted[filepath.Clean(c.SourcePath)] {
+				held = append(held, c)
+				continue
+			}
+			log.Debugf("extractor: %s was extracted", c.SourcePath)
+			c.Delete = true
+			out <- c
+		}
+		continuations = held
+	}
+	// the continuations whose first volume never came, or failed to extract
+	for _, m := range continuations {
+		log.Warnf("extractor: %s was not extracted with a first volume, skipping", m.SourcePath)
+		out <- m
+	}
+}
+
+func init() {
+	processor.Register(processor.Pre, "extract", func() processor.Processor {
+		return &Extractor{
+			WorkDir: filepath.Join(os.TempDir(), "pachinko"),
+			Formats: []string{
+				string(archive.Gzip),
+				string(archive.Rar),
+				string(archive.SevenZip),
+				string(archive.Tar),
+				string(archive.TarGzip),
+				string(archive.Zip),
+			},
+		}
+	})
+}
@@ -0,0 +1,169 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package tvmeta
+
+import (
+	"archive/zip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+func TestExtractor_Process(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extractor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "Mr.Robot.S01E01.zip")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	if _, err := w.Create("Mr.Robot.S01E01.mkv"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+
+	p := &Extractor{WorkDir: filepath.Join(dir, "work"), Formats: []string{"zip"}}
+	if err := p.Init(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	in := make(chan types.Item, 2)
+	out := make(chan types.Item, 10)
+	in <- types.Item{SourcePath: src, Source: "tv", Category: types.Archive, FileType: types.File}
+	in <- types.Item{SourcePath: filepath.Join(dir, "broken.zip"), Category: types.Archive, FileType: types.File}
+	close(in)
+	p.Process(in, out)
+	close(out)
+
+	got := map[string]types.Item{}
+	for m := range out {
+		got[m.SourcePath] = m
+	}
+	if !got[src].Delete {
+		t.Errorf("extracted archive should be marked for delete")
+	}
+	if got[filepath.Join(dir, "broken.zip")].Delete {
+		t.Errorf("failed archive should not be marked for delete")
+	}
+	extracted, ok := got[filepath.Join(dir, "work", "Mr.Robot.S01E01", "Mr.Robot.S01E01.mkv")]
+	if !ok {
+		t.Fatalf("extracted file missing from %v", got)
+	}
+	if extracted.Category != types.Video || extracted.Source != "tv" {
+		t.Errorf("got category %s source %s, want video tv", extracted.Category, extracted.Source)
+	}
+	if d, ok := got[filepath.Join(dir, "work", "Mr.Robot.S01E01")]; !ok || d.FileType != types.Directory {
+		t.Errorf("extraction dir missing from %v", got)
+	}
+}
+
+type categorizerFunc func(types.Item) types.Item
+
+func (f categorizerFunc) Categorize(m types.Item) types.Item { return f(m) }
+
+func TestExtractor_continuations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extractor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "Show.S01E01.zip")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	if _, err := w.Create("Show.S01E01.mkv"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+
+	p := &Extractor{WorkDir: filepath.Join(dir, "work"), Formats: []string{"rar", "zip"}}
+	// the configured categorizer categorizes the extracted files
+	p.UseCategorizer(categorizerFunc(func(m types.Item) types.Item {
+		m.Category = types.Text
+		return m
+	}))
+	if err := p.Init(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(dir, "Movie.part02.rar")
+	in := make(chan types.Item, 2)
+	out := make(chan types.Item, 10)
+	in <- types.Item{SourcePath: orphan, Category: types.Archive, FileType: types.File}
+	in <- types.Item{SourcePath: src, Category: types.Archive, FileType: types.File}
+	close(in)
+	p.Process(in, out)
+	close(out)
+
+	got := map[string]types.Item{}
+	for m := range out {
+		got[m.SourcePath] = m
+	}
+	if m, ok := got[orphan]; !ok || m.Delete {
+		t.Errorf("orphaned volume should be passed on without delete, got %v", got)
+	}
+	if m := got[filepath.Join(dir, "work", "Show.S01E01", "Show.S01E01.mkv")]; m.Category != types.Text {
+		t.Errorf("got category %q, want %q", m.Category, types.Text)
+	}
+}
+
+func TestExtractor_dryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extractor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "Mr.Robot.S01E01.zip")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	if _, err := w.Create("Mr.Robot.S01E01.mkv"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+
+	work := filepath.Join(dir, "work")
+	p := &Extractor{WorkDir: work, Formats: []string{"zip"}}
+	p.UseDryRun(true)
+	if err := p.Init(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	in := make(chan types.Item, 1)
+	out := make(chan types.Item, 10)
+	in <- types.Item{SourcePath: src, Category: types.Archive, FileType: types.File}
+	close(in)
+	p.Process(in, out)
+	close(out)
+
+	got := []types.Item{}
+	for m := range out {
+		got = append(got, m)
+	}
+	if len(got) != 1 || got[0].SourcePath != src || got[0].Delete {
+		t.Errorf("got %v, want the archive passed on as it is", got)
+	}
+	if _, err := os.Stat(work); !os.IsNotExist(err) {
+		t.Errorf("work dir was written to on a dry run: %v", err)
+	}
+}
@@ -9,6 +9,7 @@ package tvmeta
 
 import (
 	"context"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/rbtr/pachinko/plugin/processor"
 	"github.com/rbtr/pachinko/types"
 	"github.com/rbtr/pachinko/types/metadata/movie"
+	"github.com/rbtr/pachinko/types/metadata/video"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -24,8 +26,8 @@ var defaultMovieMatchers = []string{
 }
 
 type MoviePreProcessor struct {
-	MatcherStrings []string `mapstructure:"matchers"`
-	Sanitize       bool     `mapstructure:"sanitize-name"`
+	MatcherStrings []string `mapstructure:"matchers" description:"regular expressions that match the names and years of movies"`
+	Sanitize       bool     `mapstructure:"sanitize-name" description:"clean up the names of the movies"`
 
 	matchers []*regexp.Regexp
 }
@@ -67,9 +69,36 @@ func (p *MoviePreProcessor) extractMetadata(m types.Item) types.Item {
 	}
 	m.MovieMetadata.Title = title
 	m.MovieMetadata.ReleaseYear, _ = strconv.Atoi(year)
+	m.VideoMetadata = types.ParseVideoMetadata(filepath.Base(m.SourcePath))
 	return m
 }
 
+// fill sets each of the title and year that a hinted item wasn't hinted
+// with, and its video metadata if it has none, from its path.
+func (p *MoviePreProcessor) fill(m types.Item) types.Item {
+	extracted := p.extractMetadata(m)
+	if !hinted(m, "title") {
+		m.MovieMetadata.Title = extracted.MovieMetadata.Title
+	}
+	if !hinted(m, "year") {
+		m.MovieMetadata.ReleaseYear = extracted.MovieMetadata.ReleaseYear
+	}
+	if m.VideoMetadata == (video.Metadata{}) {
+		m.VideoMetadata = extracted.VideoMetadata
+	}
+	return m
+}
+
+// hinted tests if the hinted item was hinted with the metadata field.
+func hinted(m types.Item, field string) bool {
+	for _, f := range m.HintedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
 // identify tests if the input is matched by any of the Movie regexp.
 func (p *MoviePreProcessor) identify(m types.Item) bool {
 	for _, matcher := range p.matchers {
@@ -87,6 +116,15 @@ func (p *MoviePreProcessor) Process(in <-chan types.Item, out chan<- types.Item)
 	log.Trace("started movie_path_metadata processor")
 	for m := range in {
 		log.Tracef("movie_path_metadata: received input: %#v", m)
+		if m.Hinted {
+			// the input supplied the metadata, only fill in what it left out
+			if m.MediaType == movie.Movie {
+				m = p.fill(m)
+			}
+			log.Debugf("movie_path_metadata: %s is hinted as [%s], skipping", m.SourcePath, m.MediaType)
+			out <- m
+			continue
+		}
 		if m.Category == types.Video {
 			log.Infof("movie_path_metadata: %s category == video, testing for movie", m.SourcePath)
 			if p.identify(m) {
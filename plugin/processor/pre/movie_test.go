@@ -76,3 +76,19 @@ func TestMoviePreProcessor_identify(t *testing.T) {
 		}
 	}
 }
+
+func TestMoviePreProcessor_hinted(t *testing.T) {
+	p := &MoviePreProcessor{MatcherStrings: defaultMovieMatchers, Sanitize: true}
+	_ = p.Init(context.TODO())
+	in := make(chan types.Item, 1)
+	out := make(chan types.Item, 1)
+	// a hinted title is kept, and the missing year and video metadata are extracted
+	hinted := types.Item{Category: types.Video, Hinted: true, HintedFields: []string{"title"}, MediaType: movie.Movie, SourcePath: "/src/Finding Nemo (2003)/Finding Nemo (2003) 1080p.mkv"}
+	hinted.MovieMetadata.Title = "Hinted"
+	in <- hinted
+	close(in)
+	p.Process(in, out)
+	if m := <-out; m.MovieMetadata.Title != "Hinted" || m.MovieMetadata.ReleaseYear != 2003 || m.VideoMetadata.Resolution.Height != 1080 {
+		t.Errorf("got %#v", m)
+	}
+}
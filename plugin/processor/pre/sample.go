@@ -0,0 +1,190 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package tvmeta
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/internal/media"
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var defaultSampleMatchers = []string{
+	`(?i)(^|[\s._-])sample([\s._-]|$)`, // matches "name.sample.mkv", "sample-name.mkv"
+	`(?i)^rarbg(\.com)?$`,              // matches RARBG promo videos
+	`(?i)(^|[\s._-])(trailer|promo)$`,  // matches "name-trailer.mkv"
+}
+
+var defaultSampleDirectories = []string{
+	"sample",
+	"samples",
+	"proof",
+}
+
+// SampleDetector flags sample clips and junk videos so they are deleted
+// instead of being identified as media. Videos are flagged if their file
+// name (without extension) matches a matcher, they are in a sample directory,
+// they are smaller than [size-ratio] of the largest video next to them, or
+// they are shorter than [max-duration]. The size and duration checks are off
+// by default: in a flat downloads directory, an episode would be flagged as a
+// sample of a movie next to it.
+type SampleDetector struct {
+	MatcherStrings []string      `mapstructure:"matchers" description:"regular expressions matched against the file names of samples"`
+	Directories    []string      `mapstructure:"directories" description:"names of the directories that samples are in"`
+	SizeRatio      float64       `mapstructure:"size-ratio" description:"videos smaller than this ratio of the largest video next to them are samples, 0 is off"`
+	MaxDuration    time.Duration `mapstructure:"max-duration" description:"videos shorter than this are samples, 0 is off"`
+
+	matchers []*regexp.Regexp
+	largest  map[string]int64
+}
+
+// Validate implements the Validator interface on the SampleDetector.
+func (p *SampleDetector) Validate() error {
+	_, err := p.compile()
+	return err
+}
+
+// compile compiles the matchers.
+func (p *SampleDetector) compile() ([]*regexp.Regexp, error) {
+	matchers := []*regexp.Regexp{}
+	for _, str := range p.MatcherStrings {
+		r, err := regexp.Compile(str)
+		if err != nil {
+			return nil, errors.Wrapf(err, "sample_detector: bad matcher %q", str)
+		}
+		matchers = append(matchers, r)
+	}
+	return matchers, nil
+}
+
+func (p *SampleDetector) Init(context.Context) error {
+	log.Trace("sample_detector: initializing")
+	var err error
+	if p.matchers, err = p.compile(); err != nil {
+		return err
+	}
+	p.largest = map[string]int64{}
+	log.Tracef("sample_detector: initialized %d matchers", len(p.matchers))
+	return nil
+}
+
+// largestSibling returns the size of the largest video in the directory.
+func (p *SampleDetector) largestSibling(dir string) int64 {
+	if size, ok := p.largest[dir]; ok {
+		return size
+	}
+	var largest int64
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Errorf("sample_detector: %s", err)
+	}
+	for _, info := range infos {
+		ext := strings.ToLower(strings.Trim(filepath.Ext(info.Name()), "."))
+		for _, v := range types.VideoExtensions {
+			if ext == v && info.Size() > largest {
+				largest = info.Size()
+			}
+		}
+	}
+	p.largest[dir] = largest
+	return largest
+}
+
+func (p *SampleDetector) matchName(m types.Item) bool {
+	base := filepath.Base(m.SourcePath)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	for _, matcher := range p.matchers {
+		if matcher.MatchString(name) {
+			log.Tracef("sample_detector: regexp %s matched %s", matcher, name)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *SampleDetector) matchDirectory(m types.Item) bool {
+	dir := filepath.Base(filepath.Dir(m.SourcePath))
+	for _, d := range p.Directories {
+		if strings.EqualFold(d, dir) {
+			log.Tracef("sample_detector: %s is in a %s dir", m.SourcePath, d)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *SampleDetector) matchSize(m types.Item) bool {
+	if p.SizeRatio <= 0 {
+		return false
+	}
+	info, err := os.Stat(m.SourcePath)
+	if err != nil {
+		log.Debugf("sample_detector: %s", err)
+		return false
+	}
+	largest := p.largestSibling(filepath.Dir(m.SourcePath))
+	if float64(info.Size()) < p.SizeRatio*float64(largest) {
+		log.Tracef("sample_detector: %s is %d bytes, less than %.2f of the largest sibling %d bytes", m.SourcePath, info.Size(), p.SizeRatio, largest)
+		return true
+	}
+	return false
+}
+
+func (p *SampleDetector) matchDuration(m types.Item) bool {
+	if p.MaxDuration <= 0 {
+		return false
+	}
+	d, err := media.Duration(m.SourcePath)
+	if err != nil {
+		log.Debugf("sample_detector: can't read duration of %s: %s", m.SourcePath, err)
+		return false
+	}
+	log.Tracef("sample_detector: %s has duration %s", m.SourcePath, d)
+	return d < p.MaxDuration
+}
+
+// identify tests if the input is a sample.
+func (p *SampleDetector) identify(m types.Item) bool {
+	return p.matchName(m) || p.matchDirectory(m) || p.matchSize(m) || p.matchDuration(m)
+}
+
+func (p *SampleDetector) Process(in <-chan types.Item, out chan<- types.Item) {
+	log.Trace("started sample_detector processor")
+	for m := range in {
+		log.Tracef("sample_detector: received input: %#v", m)
+		if m.Category == types.Video {
+			if p.identify(m) {
+				log.Infof("sample_detector: %s is a sample, marking for delete", m.SourcePath)
+				m.Category = types.Sample
+				m.MediaType = ""
+				m.Delete = true
+			}
+		} else {
+			log.Debugf("sample_detector: %s category [%s] != video, skipping", m.SourcePath, m.Category)
+		}
+		out <- m
+	}
+}
+
+func init() {
+	processor.Register(processor.Pre, "sample", func() processor.Processor {
+		return &SampleDetector{
+			MatcherStrings: defaultSampleMatchers,
+			Directories:    defaultSampleDirectories,
+		}
+	})
+}
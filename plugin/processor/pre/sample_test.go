@@ -0,0 +1,107 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package tvmeta
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+)
+
+func TestSampleDetector_identify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sample_detector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]int{
+		"Mr Robot S01E01/Mr.Robot.S01E01.mkv":           1000,
+		"Mr Robot S01E01/Mr.Robot.S01E01.sample.mkv":    50,
+		"Mr Robot S01E01/Sample/Mr.Robot.S01E01.mkv":    500,
+		"Mr Robot S01E01/RARBG.com.mp4":                 500,
+		"Mr Robot S01E01/small.mkv":                     50,
+		"Finding Nemo (2003)/Finding Nemo (2003).mkv":   1000,
+		"Finding Nemo (2003)/Finding Nemo-trailer.mkv":  500,
+		"Blade Runner 2049 (2017)/Blade Runner.mkv":     1000,
+		"Blade Runner 2049 (2017)/Blade Runner.cd2.mkv": 900,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]bool{
+		"Mr Robot S01E01/Mr.Robot.S01E01.mkv":           false,
+		"Mr Robot S01E01/Mr.Robot.S01E01.sample.mkv":    true,
+		"Mr Robot S01E01/Sample/Mr.Robot.S01E01.mkv":    true,
+		"Mr Robot S01E01/RARBG.com.mp4":                 true,
+		"Mr Robot S01E01/small.mkv":                     true,
+		"Finding Nemo (2003)/Finding Nemo (2003).mkv":   false,
+		"Finding Nemo (2003)/Finding Nemo-trailer.mkv":  true,
+		"Blade Runner 2049 (2017)/Blade Runner.mkv":     false,
+		"Blade Runner 2049 (2017)/Blade Runner.cd2.mkv": false,
+	}
+
+	p := &SampleDetector{
+		MatcherStrings: defaultSampleMatchers,
+		Directories:    defaultSampleDirectories,
+		SizeRatio:      0.1,
+	}
+	_ = p.Init(context.TODO())
+	for name, want := range want {
+		if got := p.identify(types.Item{SourcePath: filepath.Join(dir, name)}); got != want {
+			t.Errorf("%s: got %t, want %t", name, got, want)
+		}
+	}
+}
+
+// TestSampleDetector_flat checks that by default, a release isn't a sample
+// of a larger one that is next to it in a flat downloads directory.
+func TestSampleDetector_flat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sample_detector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]int{
+		"Mr.Robot.S01E01.mkv":        150,
+		"Blade.Runner.2049.2017.mkv": 2000,
+	}
+	for name, size := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := processor.Registry[processor.Pre]["sample"]().(*SampleDetector)
+	_ = p.Init(context.TODO())
+	for name := range files {
+		if p.identify(types.Item{SourcePath: filepath.Join(dir, name)}) {
+			t.Errorf("%s is a sample", name)
+		}
+	}
+}
+
+func TestSampleDetector_badMatcher(t *testing.T) {
+	p := &SampleDetector{MatcherStrings: []string{`(?i)sample(`}}
+	if err := p.Validate(); err == nil {
+		t.Error("expected an error validating a bad matcher")
+	}
+	if err := p.Init(context.TODO()); err == nil {
+		t.Error("expected an error initializing a bad matcher")
+	}
+}
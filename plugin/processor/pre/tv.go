@@ -9,6 +9,7 @@ package tvmeta
 
 import (
 	"context"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/rbtr/pachinko/plugin/processor"
 	"github.com/rbtr/pachinko/types"
 	"github.com/rbtr/pachinko/types/metadata/tv"
+	"github.com/rbtr/pachinko/types/metadata/video"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -28,8 +30,8 @@ var defaultTVMatchers = []string{
 var sanitizer = regexp.MustCompile(`[^'\w]`)
 
 type TVPreProcessor struct {
-	MatcherStrings []string `mapstructure:"matchers"`
-	Sanitize       bool     `mapstructure:"sanitize-name"`
+	MatcherStrings []string `mapstructure:"matchers" description:"regular expressions that match the names, seasons, and episodes of tv"`
+	Sanitize       bool     `mapstructure:"sanitize-name" description:"clean up the names of the shows"`
 
 	matchers []*regexp.Regexp
 }
@@ -81,6 +83,30 @@ func (p *TVPreProcessor) extractMetadata(m types.Item) types.Item {
 	m.TVMetadata.ReleaseYear, _ = strconv.Atoi(year)
 	m.TVMetadata.Season.Number, _ = strconv.Atoi(season)
 	m.TVMetadata.Episode.Number, _ = strconv.Atoi(episode)
+	m.VideoMetadata = types.ParseVideoMetadata(filepath.Base(m.SourcePath))
+	return m
+}
+
+// fill sets each of the show name, year, season, and episode that a hinted
+// item wasn't hinted with, and its video metadata if it has none, from its
+// path.
+func (p *TVPreProcessor) fill(m types.Item) types.Item {
+	extracted := p.extractMetadata(m)
+	if !hinted(m, "title") {
+		m.TVMetadata.Name = extracted.TVMetadata.Name
+	}
+	if !hinted(m, "year") {
+		m.TVMetadata.ReleaseYear = extracted.TVMetadata.ReleaseYear
+	}
+	if !hinted(m, "season") {
+		m.TVMetadata.Season.Number = extracted.TVMetadata.Season.Number
+	}
+	if !hinted(m, "episode") {
+		m.TVMetadata.Episode.Number = extracted.TVMetadata.Episode.Number
+	}
+	if m.VideoMetadata == (video.Metadata{}) {
+		m.VideoMetadata = extracted.VideoMetadata
+	}
 	return m
 }
 
@@ -101,6 +127,15 @@ func (p *TVPreProcessor) Process(in <-chan types.Item, out chan<- types.Item) {
 	log.Trace("started tv_path_metadata processor")
 	for m := range in {
 		log.Tracef("tv_path_metadata: received input: %#v", m)
+		if m.Hinted {
+			// the input supplied the metadata, only fill in what it left out
+			if m.MediaType == tv.TV {
+				m = p.fill(m)
+			}
+			log.Debugf("tv_path_metadata: %s is hinted as [%s], skipping", m.SourcePath, m.MediaType)
+			out <- m
+			continue
+		}
 		if m.Category == types.Video {
 			log.Infof("tv_path_metadata: %s category == video, testing for TV", m.SourcePath)
 			if p.identify(m) {
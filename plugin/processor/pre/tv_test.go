@@ -76,3 +76,36 @@ func TestTVPreProcessor_identify(t *testing.T) {
 		}
 	}
 }
+
+func TestTVPreProcessor_hinted(t *testing.T) {
+	p := &TVPreProcessor{MatcherStrings: defaultTVMatchers, Sanitize: true}
+	_ = p.Init(context.TODO())
+	in := make(chan types.Item, 4)
+	out := make(chan types.Item, 4)
+	// a tv path hinted as a movie is left alone
+	in <- types.Item{Category: types.Video, Hinted: true, MediaType: "movie", SourcePath: "/src/Show.S01E02.mkv"}
+	// a hinted show name is kept, and the missing season and episode are extracted
+	hinted := types.Item{Category: types.Video, Hinted: true, HintedFields: []string{"title"}, MediaType: tv.TV, SourcePath: "/src/Show.S01E02.1080p.mkv"}
+	hinted.TVMetadata.Name = "Hinted"
+	in <- hinted
+	// a hinted episode is kept, and the missing name and season are extracted
+	hinted = types.Item{Category: types.Video, Hinted: true, HintedFields: []string{"episode"}, MediaType: tv.TV, SourcePath: "/src/Show.S01E02.mkv"}
+	hinted.TVMetadata.Episode.Number = 3
+	in <- hinted
+	// a hinted season 0 is kept
+	in <- types.Item{Category: types.Video, Hinted: true, HintedFields: []string{"season"}, MediaType: tv.TV, SourcePath: "/src/Show.S01E02.mkv"}
+	close(in)
+	p.Process(in, out)
+	if m := <-out; m.MediaType != "movie" || m.TVMetadata.Name != "" {
+		t.Errorf("got %#v", m)
+	}
+	if m := <-out; m.TVMetadata.Name != "Hinted" || m.TVMetadata.Season.Number != 1 || m.TVMetadata.Episode.Number != 2 || m.VideoMetadata.Resolution.Height != 1080 {
+		t.Errorf("got %#v", m)
+	}
+	if m := <-out; m.TVMetadata.Name != "Show" || m.TVMetadata.Season.Number != 1 || m.TVMetadata.Episode.Number != 3 {
+		t.Errorf("got %#v", m)
+	}
+	if m := <-out; m.TVMetadata.Season.Number != 0 || m.TVMetadata.Episode.Number != 2 {
+		t.Errorf("got %#v", m)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package script
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/starlarkstruct"
+)
+
+// newHelpers returns the modules predeclared for the scripts.
+func newHelpers() starlark.StringDict {
+	// compiled regexps are cached by pattern, a script is only ever run by
+	// one goroutine
+	cache := map[string]*regexp.Regexp{}
+	compile := func(pattern string) (*regexp.Regexp, error) {
+		if r, ok := cache[pattern]; ok {
+			return r, nil
+		}
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		cache[pattern] = r
+		return r, nil
+	}
+
+	// find returns the groups of the first match of the pattern, or None
+	find := func(anchored bool) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+		return func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var pattern, s string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "s", &s); err != nil {
+				return nil, err
+			}
+			if anchored {
+				pattern = `^(?:` + pattern + `)`
+			}
+			r, err := compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			groups := r.FindStringSubmatch(s)
+			if groups == nil {
+				return starlark.None, nil
+			}
+			t := make(starlark.Tuple, len(groups))
+			for i, g := range groups {
+				t[i] = starlark.String(g)
+			}
+			return t, nil
+		}
+	}
+
+	sub := func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var pattern, repl, s string
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "repl", &repl, "s", &s); err != nil {
+			return nil, err
+		}
+		r, err := compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.String(r.ReplaceAllString(s, repl)), nil
+	}
+
+	// str wraps a func of a string as a builtin
+	str := func(f func(string) string) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+		return func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+			return starlark.String(f(s)), nil
+		}
+	}
+
+	join := func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, errors.Errorf("%s: unexpected keyword arguments", b.Name())
+		}
+		elems := make([]string, len(args))
+		for i, a := range args {
+			s, ok := starlark.AsString(a)
+			if !ok {
+				return nil, errors.Errorf("%s: argument %d is %s, not a string", b.Name(), i+1, a.Type())
+			}
+			elems[i] = s
+		}
+		return starlark.String(filepath.Join(elems...)), nil
+	}
+
+	// sprintf formats with the verbs of go's fmt, for padding numbers
+	sprintf := func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, errors.Errorf("%s: unexpected keyword arguments", b.Name())
+		}
+		if len(args) == 0 {
+			return nil, errors.Errorf("%s: missing format", b.Name())
+		}
+		format, ok := starlark.AsString(args[0])
+		if !ok {
+			return nil, errors.Errorf("%s: format is %s, not a string", b.Name(), args[0].Type())
+		}
+		vals := make([]interface{}, len(args)-1)
+		for i, a := range args[1:] {
+			switch v := a.(type) {
+			case starlark.Int:
+				n, ok := v.Int64()
+				if !ok {
+					return nil, errors.Errorf("%s: argument %d is too large", b.Name(), i+2)
+				}
+				vals[i] = n
+			case starlark.Float:
+				vals[i] = float64(v)
+			case starlark.Bool:
+				vals[i] = bool(v)
+			case starlark.String:
+				vals[i] = string(v)
+			default:
+				vals[i] = v.String()
+			}
+		}
+		return starlark.String(fmt.Sprintf(format, vals...)), nil
+	}
+
+	return starlark.StringDict{
+		"sprintf": starlark.NewBuiltin("sprintf", sprintf),
+		"re": &starlarkstruct.Module{
+			Name: "re",
+			Members: starlark.StringDict{
+				"match":  starlark.NewBuiltin("re.match", find(true)),
+				"search": starlark.NewBuiltin("re.search", find(false)),
+				"sub":    starlark.NewBuiltin("re.sub", sub),
+			},
+		},
+		"path": &starlarkstruct.Module{
+			Name: "path",
+			Members: starlark.StringDict{
+				"join":  starlark.NewBuiltin("path.join", join),
+				"base":  starlark.NewBuiltin("path.base", str(filepath.Base)),
+				"dir":   starlark.NewBuiltin("path.dir", str(filepath.Dir)),
+				"ext":   starlark.NewBuiltin("path.ext", str(filepath.Ext)),
+				"clean": starlark.NewBuiltin("path.clean", str(filepath.Clean)),
+			},
+		},
+		"json": starlarkjson.Module,
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+)
+
+// Script runs a Starlark function against each item in the datastream. The
+// function is passed the item as a dict, with the same keys as its json, and
+// returns the changed dict, or None if it changed the dict in place.
+type Script struct {
+	// File is the path of the script
+	File string `mapstructure:"file" description:"path of the script"`
+	// Script is the source of the script, if File isn't set
+	Script string `mapstructure:"script" description:"source of the script, if file isn't set"`
+	// Function is the name of the function to call with each item
+	Function string `mapstructure:"function" description:"name of the function to call with each item"`
+
+	fn     starlark.Value
+	thread *starlark.Thread
+}
+
+func (p *Script) Init(context.Context) error {
+	log.Trace("script: initializing")
+	var src interface{}
+	filename := p.File
+	switch {
+	case p.File != "":
+		b, err := ioutil.ReadFile(p.File)
+		if err != nil {
+			return errors.Wrap(err, "script")
+		}
+		src = b
+	case p.Script != "":
+		filename = "script"
+		src = p.Script
+	default:
+		return errors.New("script: file or script must be set")
+	}
+	p.thread = &starlark.Thread{
+		Name: filename,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Infof("script: %s", msg)
+		},
+	}
+	globals, err := starlark.ExecFile(p.thread, filename, src, newHelpers())
+	if err != nil {
+		return errors.Wrap(err, "script")
+	}
+	globals.Freeze()
+	fn, ok := globals[p.Function]
+	if !ok {
+		return errors.Errorf("script: %s does not define %s", filename, p.Function)
+	}
+	if _, ok := fn.(starlark.Callable); !ok {
+		return errors.Errorf("script: %s in %s is not a function", p.Function, filename)
+	}
+	p.fn = fn
+	log.Tracef("script: initialized %s from %s", p.Function, filename)
+	return nil
+}
+
+// fill adds the fields of the type that json omits when they are empty to
+// the decoded object, so that the script can index them.
+func fill(t reflect.Type, obj map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if f.Anonymous && tag[0] == "" && f.Type.Kind() == reflect.Struct {
+			fill(f.Type, obj)
+			continue
+		}
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+		v, ok := obj[tag[0]]
+		if !ok {
+			if f.Type.Kind() == reflect.Map {
+				v = map[string]interface{}{}
+			} else {
+				v = reflect.Zero(f.Type).Interface()
+			}
+			obj[tag[0]] = v
+		}
+		if nested, ok := v.(map[string]interface{}); ok && f.Type.Kind() == reflect.Struct {
+			fill(f.Type, nested)
+		}
+	}
+}
+
+// toValue converts the item to a Starlark dict.
+func toValue(thread *starlark.Thread, m types.Item) (starlark.Value, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, err
+	}
+	fill(reflect.TypeOf(m), obj)
+	if b, err = json.Marshal(obj); err != nil {
+		return nil, err
+	}
+	return starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(b)}, nil)
+}
+
+// fromValue converts a Starlark dict back to an item.
+func fromValue(thread *starlark.Thread, v starlark.Value) (types.Item, error) {
+	var m types.Item
+	if _, ok := v.(*starlark.Dict); !ok {
+		return m, errors.Errorf("returned %s, not a dict", v.Type())
+	}
+	s, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{v}, nil)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal([]byte(s.(starlark.String)), &m); err != nil {
+		return m, err
+	}
+	if len(m.Identifiers) == 0 {
+		m.Identifiers = nil
+	}
+	return m, nil
+}
+
+// process calls the function with the item and returns the item it changed.
+func (p *Script) process(m types.Item) (types.Item, error) {
+	arg, err := toValue(p.thread, m)
+	if err != nil {
+		return m, err
+	}
+	ret, err := starlark.Call(p.thread, p.fn, starlark.Tuple{arg}, nil)
+	if err != nil {
+		return m, err
+	}
+	if ret == starlark.None {
+		ret = arg
+	}
+	return fromValue(p.thread, ret)
+}
+
+func (p *Script) Process(in <-chan types.Item, out chan<- types.Item) {
+	log.Trace("started script processor")
+	for m := range in {
+		log.Tracef("script: received input %#v", m)
+		changed, err := p.process(m)
+		if err != nil {
+			if evalErr, ok := err.(*starlark.EvalError); ok {
+				err = errors.New(evalErr.Backtrace())
+			}
+			log.Errorf("script: error processing %s, passing it through unchanged: %s", m.SourcePath, err)
+			out <- m
+			continue
+		}
+		out <- changed
+	}
+}
+
+func init() {
+	for _, t := range processor.Types {
+		processor.Register(t, "script", func() processor.Processor {
+			return &Script{
+				Function: "process",
+			}
+		})
+	}
+}
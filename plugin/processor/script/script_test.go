@@ -0,0 +1,138 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package script
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rbtr/pachinko/types"
+	"github.com/rbtr/pachinko/types/metadata/tv"
+)
+
+func TestScript_process(t *testing.T) {
+	item := types.Item{
+		Category:   types.Video,
+		FileType:   types.File,
+		MediaType:  tv.TV,
+		ModTime:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Size:       1000,
+		SourcePath: "/src/Mr.Robot.S01E02.mkv",
+		TVMetadata: tv.Metadata{Name: "Mr Robot", Episode: tv.Episode{Number: 2, Season: tv.Season{Number: 1}}},
+	}
+	tests := []struct {
+		name   string
+		script string
+		want   func(types.Item) types.Item
+	}{
+		{
+			name: "unchanged",
+			script: `
+def process(item):
+    return item
+`,
+			want: func(m types.Item) types.Item { return m },
+		},
+		{
+			name: "in place",
+			script: `
+def process(item):
+    if item["size"] < 5000 and not item["delete"]:
+        item["delete"] = True
+`,
+			want: func(m types.Item) types.Item {
+				m.Delete = true
+				return m
+			},
+		},
+		{
+			name: "reroute",
+			script: `
+def process(item):
+    tv = item["tv-metadata"]
+    item["destination-path"] = path.join("/tv", tv["name"], sprintf("Season %02d", tv["season"]["number"]), path.base(item["source-path"]))
+    item["identifiers"]["custom"] = "1"
+    return item
+`,
+			want: func(m types.Item) types.Item {
+				m.DestinationPath = "/tv/Mr Robot/Season 01/Mr.Robot.S01E02.mkv"
+				m.Identifiers = map[string]string{"custom": "1"}
+				return m
+			},
+		},
+		{
+			name: "regexp",
+			script: `
+def process(item):
+    groups = re.search(r"S(\d+)E(\d+)", item["source-path"])
+    if groups:
+        item["tv-metadata"]["number"] = int(groups[2]) + 10
+    item["tv-metadata"]["name"] = re.sub(r"\s+", ".", item["tv-metadata"]["name"])
+    if re.match("Robot", item["tv-metadata"]["name"]):
+        item["delete"] = True
+`,
+			want: func(m types.Item) types.Item {
+				m.TVMetadata.Name = "Mr.Robot"
+				m.TVMetadata.Episode.Number = 12
+				return m
+			},
+		},
+		{
+			name: "error",
+			script: `
+def process(item):
+    item["size"] = "big"
+`,
+			want: func(m types.Item) types.Item { return m },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Script{Script: tt.script, Function: "process"}
+			if err := p.Init(context.TODO()); err != nil {
+				t.Fatal(err)
+			}
+			in := make(chan types.Item, 1)
+			out := make(chan types.Item, 1)
+			in <- item.Copy()
+			close(in)
+			p.Process(in, out)
+			got := <-out
+			want := tt.want(item.Copy())
+			if !got.ModTime.Equal(want.ModTime) {
+				t.Errorf("got mod time %s, want %s", got.ModTime, want.ModTime)
+			}
+			got.ModTime = want.ModTime
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestScript_Init(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Script
+	}{
+		{"no script", &Script{Function: "process"}},
+		{"syntax error", &Script{Script: "def process(item)", Function: "process"}},
+		{"missing function", &Script{Script: "x = 1", Function: "process"}},
+		{"not a function", &Script{Script: "process = 1", Function: "process"}},
+		{"missing file", &Script{File: "/does/not/exist.star", Function: "process"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.Init(context.TODO()); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
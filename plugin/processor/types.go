@@ -24,6 +24,33 @@ type Processor interface {
 	Process(<-chan types.Item, chan<- types.Item)
 }
 
+// Validator is implemented by Processors that check their options when the
+// config is loaded, before they are initialized, so that mistakes like
+// missing keys are found without connecting to anything.
+type Validator interface {
+	Validate() error
+}
+
+// Categorizer categorizes items, like the categorizer that runs before the
+// processors does.
+type Categorizer interface {
+	Categorize(types.Item) types.Item
+}
+
+// CategorizerUser is implemented by Processors that create items, so that
+// they are categorized by the configured categorizer. It is set before the
+// Processor is initialized.
+type CategorizerUser interface {
+	UseCategorizer(Categorizer)
+}
+
+// DryRunUser is implemented by Processors that change the filesystem, so that
+// they only log the changes on dry runs. It is set before the Processor is
+// initialized.
+type DryRunUser interface {
+	UseDryRun(bool)
+}
+
 type Func func(<-chan types.Item, chan<- types.Item)
 
 func (Func) Init(context.Context) error {
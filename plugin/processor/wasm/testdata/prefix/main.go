@@ -0,0 +1,46 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+// prefix is a module for the wasm processor tests. It sets the destination
+// of the item to its source path under the PREFIX env, or under the contents
+// of /config/prefix if that is mounted.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+func main() {
+	item := map[string]interface{}{}
+	if err := json.NewDecoder(os.Stdin).Decode(&item); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	src, _ := item["source-path"].(string)
+	switch src {
+	case "fail":
+		fmt.Fprintln(os.Stderr, "failing")
+		os.Exit(3)
+	case "loop":
+		for {
+		}
+	case "skip":
+		return
+	}
+	prefix := os.Getenv("PREFIX")
+	if b, err := ioutil.ReadFile("/config/prefix"); err == nil {
+		prefix = strings.TrimSpace(string(b))
+	}
+	item["destination-path"] = path.Join(prefix, src)
+	_ = json.NewEncoder(os.Stdout).Encode(item)
+}
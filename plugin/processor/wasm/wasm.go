@@ -0,0 +1,195 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbtr/pachinko/plugin/processor"
+	"github.com/rbtr/pachinko/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// cache shares the compiled modules between the instances of the processor
+// and across runs.
+var cache = wazero.NewCompilationCache()
+
+// Mount grants a module access to a directory.
+type Mount struct {
+	// Host is the directory to mount
+	Host string `mapstructure:"host" description:"the directory to mount"`
+	// Guest is the path the module sees it at, defaults to the host path
+	Guest string `mapstructure:"guest" description:"the path the module sees it at, defaults to the host path"`
+	// ReadOnly mounts the directory read-only
+	ReadOnly bool `mapstructure:"read-only" description:"mount the directory read-only"`
+}
+
+// Wasm runs a WebAssembly module, compiled for WASI, against each item in the
+// datastream. The module is run once per item, sandboxed, with the item's
+// json on its stdin, and writes the changed item's json to its stdout.
+//
+// The module has no access to the filesystem, the environment, or the
+// network, except for the mounts and env that are granted to it.
+type Wasm struct {
+	// File is the path of the module
+	File string `mapstructure:"file" description:"path of the module"`
+	// Args are the arguments the module is run with
+	Args []string `mapstructure:"args" description:"arguments the module is run with"`
+	// Env are the environment variables the module is run with
+	Env map[string]string `mapstructure:"env" description:"environment variables the module is run with"`
+	// Mounts are the directories the module can access
+	Mounts []Mount `mapstructure:"mounts" description:"directories the module can access"`
+	// Timeout for the module to process an item
+	Timeout time.Duration `mapstructure:"timeout" description:"time for the module to process an item"`
+
+	ctx      context.Context
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	config   wazero.ModuleConfig
+}
+
+func (p *Wasm) Init(ctx context.Context) error {
+	log.Trace("wasm: initializing")
+	if p.File == "" {
+		return errors.New("wasm: file must be set")
+	}
+	b, err := ioutil.ReadFile(p.File)
+	if err != nil {
+		return errors.Wrap(err, "wasm")
+	}
+	p.ctx = ctx
+	p.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCompilationCache(cache).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, p.runtime); err != nil {
+		_ = p.runtime.Close(ctx)
+		return errors.Wrap(err, "wasm")
+	}
+	if p.compiled, err = p.runtime.CompileModule(ctx, b); err != nil {
+		_ = p.runtime.Close(ctx)
+		return errors.Wrapf(err, "wasm: error compiling %s", p.File)
+	}
+
+	fs := wazero.NewFSConfig()
+	for _, m := range p.Mounts {
+		guest := m.Guest
+		if guest == "" {
+			guest = m.Host
+		}
+		if m.ReadOnly {
+			fs = fs.WithReadOnlyDirMount(m.Host, guest)
+		} else {
+			fs = fs.WithDirMount(m.Host, guest)
+		}
+	}
+	// each instance is anonymous, so that they don't collide
+	p.config = wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{p.File}, p.Args...)...).
+		WithFSConfig(fs).
+		WithStderr(&logWriter{})
+	for k, v := range p.Env {
+		p.config = p.config.WithEnv(k, v)
+	}
+	log.Tracef("wasm: initialized %s with %d mounts", p.File, len(p.Mounts))
+	return nil
+}
+
+// logWriter logs the lines written to it.
+type logWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err == io.EOF {
+			// keep the partial line for the next write
+			w.buf.WriteString(line)
+			return len(b), nil
+		}
+		log.Infof("wasm: %s", line[:len(line)-1])
+	}
+}
+
+// process runs the module with the item and returns the item it wrote.
+func (p *Wasm) process(m types.Item) (types.Item, error) {
+	in, err := json.Marshal(m)
+	if err != nil {
+		return m, err
+	}
+	ctx := p.ctx
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	var out bytes.Buffer
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, p.config.
+		WithStdin(bytes.NewReader(append(in, '\n'))).
+		WithStdout(&out))
+	if mod != nil {
+		_ = mod.Close(ctx)
+	}
+	if err != nil {
+		if exitErr, ok := err.(*sys.ExitError); ok {
+			switch exitErr.ExitCode() {
+			case sys.ExitCodeDeadlineExceeded:
+				return m, errors.Errorf("timed out after %s", p.Timeout)
+			case sys.ExitCodeContextCanceled:
+				return m, errors.New("canceled")
+			}
+			return m, errors.Errorf("exited with code %d", exitErr.ExitCode())
+		}
+		return m, err
+	}
+	// the module didn't change the item
+	if len(bytes.TrimSpace(out.Bytes())) == 0 {
+		return m, nil
+	}
+	var changed types.Item
+	if err := json.Unmarshal(out.Bytes(), &changed); err != nil {
+		return m, errors.Wrap(err, "error reading item")
+	}
+	return changed, nil
+}
+
+func (p *Wasm) Process(in <-chan types.Item, out chan<- types.Item) {
+	log.Trace("started wasm processor")
+	defer p.runtime.Close(p.ctx)
+	for m := range in {
+		log.Tracef("wasm: received input %#v", m)
+		changed, err := p.process(m)
+		if err != nil {
+			log.Errorf("wasm: error processing %s, passing it through unchanged: %s", m.SourcePath, err)
+			out <- m
+			continue
+		}
+		out <- changed
+	}
+}
+
+func init() {
+	for _, t := range processor.Types {
+		processor.Register(t, "wasm", func() processor.Processor {
+			return &Wasm{
+				Timeout: 10 * time.Second,
+			}
+		})
+	}
+}
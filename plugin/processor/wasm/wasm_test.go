@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 The Pachinko Authors
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package wasm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rbtr/pachinko/types"
+)
+
+// build compiles the test module, skipping the test if the toolchain can't
+// target wasip1.
+func build(t *testing.T, dir string) string {
+	out := filepath.Join(dir, "prefix.wasm")
+	cmd := exec.Command("go", "build", "-o", out, "./testdata/prefix")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("can't build the wasip1 test module: %s: %s", err, b)
+	}
+	return out
+}
+
+func TestWasm_process(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	module := build(t, dir)
+	config := filepath.Join(dir, "config")
+	if err := os.Mkdir(config, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(config, "prefix"), []byte("/mounted\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mounts  []Mount
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{name: "env", src: "a.mkv", want: "/env/a.mkv"},
+		{name: "mount", mounts: []Mount{{Host: config, Guest: "/config", ReadOnly: true}}, src: "a.mkv", want: "/mounted/a.mkv"},
+		{name: "unchanged", src: "skip", want: ""},
+		{name: "exit", src: "fail", wantErr: true},
+		{name: "timeout", src: "loop", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Wasm{
+				File:    module,
+				Env:     map[string]string{"PREFIX": "/env"},
+				Mounts:  tt.mounts,
+				Timeout: 5 * time.Second,
+			}
+			if err := p.Init(context.TODO()); err != nil {
+				t.Fatal(err)
+			}
+			defer p.runtime.Close(context.TODO())
+			got, err := p.process(types.Item{SourcePath: tt.src, FileType: types.File})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if got.DestinationPath != tt.want {
+				t.Errorf("got %s, want %s", got.DestinationPath, tt.want)
+			}
+			if got.SourcePath != tt.src || got.FileType != types.File {
+				t.Errorf("got %#v, lost the rest of the item", got)
+			}
+		})
+	}
+}
+
+func TestWasm_Init(t *testing.T) {
+	if err := (&Wasm{}).Init(context.TODO()); err == nil {
+		t.Error("expected an error without a file")
+	}
+	f, err := ioutil.TempFile("", "wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString("not wasm")
+	f.Close()
+	if err := (&Wasm{File: f.Name()}).Init(context.TODO()); err == nil {
+		t.Error("expected an error compiling an invalid module")
+	}
+}
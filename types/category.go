@@ -12,6 +12,7 @@ type Category string
 const (
 	Archive  Category = "archive"
 	Image    Category = "image"
+	Sample   Category = "sample"
 	Subtitle Category = "subtitle"
 	Text     Category = "text"
 	Unknown  Category = ""
@@ -39,11 +40,16 @@ var ImageExtensions = []string{
 	"jpg",
 	"png",
 	"tiff",
+	"webp",
 }
 
 var SubtitleExtensions = []string{
+	"ass",
+	"idx",
 	"srt",
+	"ssa",
 	"sub",
+	"vtt",
 }
 
 var TextExtensions = []string{
@@ -56,9 +62,16 @@ var TextExtensions = []string{
 var VideoExtensions = []string{
 	"avi",
 	"divx",
+	"m2ts",
 	"m4v",
 	"mkv",
 	"mov",
 	"mp4",
+	"mpeg",
+	"mpg",
+	"mts",
+	"ts",
+	"webm",
+	"wmv",
 	"xvid",
 }
@@ -9,6 +9,7 @@ package types
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/rbtr/pachinko/types/metadata"
 	"github.com/rbtr/pachinko/types/metadata/movie"
@@ -23,18 +24,68 @@ const (
 	File
 )
 
+// String formats the FileType.
+func (t FileType) String() string {
+	if t == File {
+		return "file"
+	}
+	return "directory"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t FileType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *FileType) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "file":
+		*t = File
+	case "directory":
+		*t = Directory
+	default:
+		return fmt.Errorf("unknown file type %s", b)
+	}
+	return nil
+}
+
 // Item is the container struct for a file flowing through the entire pipeline.
+// Hinted is set by inputs that supplied the MediaType and metadata, so that
+// the pre-processors don't extract them from the path, and HintedFields are
+// the metadata fields that were supplied, any of title, year, season, and
+// episode, so that only the others are filled in from the path. Outcome is set on the
+// items that an output receives because it runs after another output, to
+// what that output did with the item: moved, deleted, failed, or skipped.
 type Item struct {
-	Category        Category
-	Delete          bool
-	DestinationPath string
-	FileType        FileType
-	Identifiers     map[string]string
-	MediaType       metadata.MediaType
-	MovieMetadata   movie.Metadata
-	SourcePath      string
-	TVMetadata      tv.Metadata
-	VideoMetadata   video.Metadata
+	Category        Category           `json:"category,omitempty"`
+	Delete          bool               `json:"delete,omitempty"`
+	DestinationPath string             `json:"destination-path,omitempty"`
+	FileType        FileType           `json:"file-type"`
+	Hinted          bool               `json:"hinted,omitempty"`
+	HintedFields    []string           `json:"hinted-fields,omitempty"`
+	Identifiers     map[string]string  `json:"identifiers,omitempty"`
+	MediaType       metadata.MediaType `json:"media-type,omitempty"`
+	ModTime         time.Time          `json:"mod-time"`
+	MovieMetadata   movie.Metadata     `json:"movie-metadata"`
+	Outcome         string             `json:"outcome,omitempty"`
+	Size            int64              `json:"size,omitempty"`
+	Source          string             `json:"source,omitempty"`
+	SourcePath      string             `json:"source-path,omitempty"`
+	TVMetadata      tv.Metadata        `json:"tv-metadata"`
+	VideoMetadata   video.Metadata     `json:"video-metadata"`
+}
+
+// Copy returns a copy of the Item that shares nothing with it.
+func (m *Item) Copy() Item {
+	c := *m
+	if m.Identifiers != nil {
+		c.Identifiers = make(map[string]string, len(m.Identifiers))
+		for k, v := range m.Identifiers {
+			c.Identifiers[k] = v
+		}
+	}
+	return c
 }
 
 // String formats the Item struct.
@@ -7,7 +7,14 @@ file, You can obtain one at https://mozilla.org/MPL/2.0/.
 */
 package types
 
-import "regexp"
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rbtr/pachinko/types/metadata/video"
+)
 
 // AudioChannels regexp constants.
 var AudioChannels = map[string]*regexp.Regexp{
@@ -18,7 +25,13 @@ var AudioChannels = map[string]*regexp.Regexp{
 
 // AudioFormats regexp constants.
 var AudioFormats = map[string]*regexp.Regexp{
-	"aac": regexp.MustCompile("aac"),
+	"aac":    regexp.MustCompile("aac"),
+	"ac3":    regexp.MustCompile(`\bac-?3|\bdd[\s.]?[25]\.[01]`),
+	"eac3":   regexp.MustCompile(`e-?ac-?3|\bddp|\bdd\+`),
+	"dts":    regexp.MustCompile(`\bdts\b`),
+	"dts-hd": regexp.MustCompile(`\bdts.?(hd|ma)\b`),
+	"flac":   regexp.MustCompile(`\bflac\b`),
+	"truehd": regexp.MustCompile(`\btrue.?hd\b`),
 }
 
 // ColorFormats regexp constants.
@@ -27,18 +40,36 @@ var ColorFormats = map[string]*regexp.Regexp{
 	"10 bit": regexp.MustCompile(`10.bit`),
 }
 
+// HDRFormats regexp constants.
+var HDRFormats = map[string]*regexp.Regexp{
+	"dolby vision": regexp.MustCompile(`\b(dv|dovi|dolby.?vision)\b`),
+	"hdr10":        regexp.MustCompile(`\bhdr(10)?\b`),
+	"hdr10+":       regexp.MustCompile(`\bhdr10(\+|plus)`),
+	"hlg":          regexp.MustCompile(`\bhlg\b`),
+}
+
 // Resolutions regexp constants.
 var Resolutions = map[string]*regexp.Regexp{
+	"2160p": regexp.MustCompile(`\b(2160p?|4k|uhd)\b`),
 	"1080p": regexp.MustCompile(`\b1080p?`),
 	"720p":  regexp.MustCompile(`\b720p?`),
 	"480p":  regexp.MustCompile(`\b480p?`),
 }
 
+// resolutions are the dimensions of the Resolutions.
+var resolutions = map[string]video.Resolution{
+	"2160p": {Width: 3840, Height: 2160},
+	"1080p": {Width: 1920, Height: 1080},
+	"720p":  {Width: 1280, Height: 720},
+	"480p":  {Width: 720, Height: 480},
+}
+
 // Sources regexp constants.
 var Sources = map[string]*regexp.Regexp{
-	"bluray": regexp.MustCompile("bluray"),
+	"bluray": regexp.MustCompile(`blu-?ray|\bb[dr]rip\b`),
 	"dvd":    regexp.MustCompile("dvd"),
 	"hdtv":   regexp.MustCompile("hdtv"),
+	"web":    regexp.MustCompile(`\bweb(-?dl|-?rip)?\b`),
 }
 
 // TVSeason regexp constants.
@@ -56,3 +87,42 @@ var VideoFormats = map[string]*regexp.Regexp{
 	"x264":  regexp.MustCompile(`x\.?264`),
 	"x265":  regexp.MustCompile(`x\.?265`),
 }
+
+// bestMatch returns the key of the matcher with the longest match in s, so
+// that the most specific of overlapping matchers wins.
+func bestMatch(matchers map[string]*regexp.Regexp, s string) string {
+	keys := make([]string, 0, len(matchers))
+	for k := range matchers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	best, length := "", 0
+	for _, k := range keys {
+		if loc := matchers[k].FindStringIndex(s); loc != nil && loc[1]-loc[0] > length {
+			best, length = k, loc[1]-loc[0]
+		}
+	}
+	return best
+}
+
+// ParseVideoMetadata parses the release tags in a file name in to video
+// metadata. Tags that aren't found are left empty.
+func ParseVideoMetadata(name string) video.Metadata {
+	name = strings.ToLower(name)
+	md := video.Metadata{
+		AudioFormat: bestMatch(AudioFormats, name),
+		HDR:         bestMatch(HDRFormats, name),
+		Resolution:  resolutions[bestMatch(Resolutions, name)],
+		Source:      bestMatch(Sources, name),
+	}
+	// dolby vision releases often also carry an hdr10 base layer
+	if HDRFormats["dolby vision"].MatchString(name) {
+		md.HDR = "dolby vision"
+	}
+	if channels := bestMatch(AudioChannels, name); channels != "" {
+		parts := strings.Split(channels, ".")
+		md.AudioChannels.FullRange, _ = strconv.Atoi(parts[0])
+		md.AudioChannels.LimitedRange, _ = strconv.Atoi(parts[1])
+	}
+	return md
+}
@@ -10,6 +10,8 @@ package types
 import (
 	"regexp"
 	"testing"
+
+	"github.com/rbtr/pachinko/types/metadata/video"
 )
 
 func matchHelper(t *testing.T, name string, matcher map[string]*regexp.Regexp) {
@@ -26,7 +28,54 @@ func TestMatchers(t *testing.T) {
 	matchHelper(t, "AudioChannels", AudioChannels)
 	matchHelper(t, "AudioFormats", AudioFormats)
 	matchHelper(t, "ColorFormats", ColorFormats)
+	matchHelper(t, "HDRFormats", HDRFormats)
 	matchHelper(t, "VideoFormats", VideoFormats)
 	matchHelper(t, "Resolutions", Resolutions)
 	matchHelper(t, "Sources", Sources)
 }
+
+func TestParseVideoMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		want video.Metadata
+	}{
+		{
+			name: "Heat.1995.2160p.UHD.BluRay.DTS-HD.MA.5.1.DV.HDR10.x265.mkv",
+			want: video.Metadata{
+				AudioChannels: video.AudioChannels{FullRange: 5, LimitedRange: 1},
+				AudioFormat:   "dts-hd",
+				HDR:           "dolby vision",
+				Resolution:    video.Resolution{Width: 3840, Height: 2160},
+				Source:        "bluray",
+			},
+		},
+		{
+			name: "Mr.Robot.S01E01.1080p.WEB-DL.DDP5.1.H.264.mkv",
+			want: video.Metadata{
+				AudioChannels: video.AudioChannels{FullRange: 5, LimitedRange: 1},
+				AudioFormat:   "eac3",
+				Resolution:    video.Resolution{Width: 1920, Height: 1080},
+				Source:        "web",
+			},
+		},
+		{
+			name: "Fargo S01E03 HDR10+ TrueHD 7.1.mkv",
+			want: video.Metadata{
+				AudioChannels: video.AudioChannels{FullRange: 7, LimitedRange: 1},
+				AudioFormat:   "truehd",
+				HDR:           "hdr10+",
+			},
+		},
+		{
+			name: "Alien (1979).mkv",
+			want: video.Metadata{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseVideoMetadata(tt.name); got != tt.want {
+				t.Errorf("ParseVideoMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,6 @@ const Movie metadata.MediaType = "movie"
 
 // Metadata contains movie metadata.
 type Metadata struct {
-	Title       string
-	ReleaseYear int
+	Title       string `json:"title"`
+	ReleaseYear int    `json:"release-year"`
 }
@@ -18,22 +18,22 @@ const TV metadata.MediaType = "tv"
 
 // Season contains the TV Season metadata.
 type Season struct {
-	Title  string
-	Number int
+	Title  string `json:"title"`
+	Number int    `json:"number"`
 }
 
 // Episode contains the TV Episode metadata.
 type Episode struct {
-	Title          string
-	Number         int
-	AbsoluteNumber int
-	Season         Season
-	AirDate        time.Time
+	Title          string    `json:"title"`
+	Number         int       `json:"number"`
+	AbsoluteNumber int       `json:"absolute-number"`
+	Season         Season    `json:"season"`
+	AirDate        time.Time `json:"air-date"`
 }
 
 // Metadata contains TV metadata.
 type Metadata struct {
-	Name        string
-	ReleaseYear int
+	Name        string `json:"name"`
+	ReleaseYear int    `json:"release-year"`
 	Episode
 }
@@ -18,8 +18,8 @@ const Video metadata.MediaCategory = "video"
 
 // AudioChannels contains video metadata.
 type AudioChannels struct {
-	FullRange    int
-	LimitedRange int
+	FullRange    int `json:"full-range"`
+	LimitedRange int `json:"limited-range"`
 }
 
 // String formats the AudioChannels struct.
@@ -29,7 +29,8 @@ func (audio *AudioChannels) String() string {
 
 // Resolution contains video metadata.
 type Resolution struct {
-	Width, Height int
+	Width  int `json:"width"`
+	Height int `json:"height"`
 }
 
 // String formats the Resolution struct.
@@ -39,6 +40,9 @@ func (rez *Resolution) String() string {
 
 // Metadata contains Video metadata.
 type Metadata struct {
-	Resolution    Resolution
-	AudioChannels AudioChannels
+	AudioChannels AudioChannels `json:"audio-channels"`
+	AudioFormat   string        `json:"audio-format,omitempty"`
+	HDR           string        `json:"hdr,omitempty"`
+	Resolution    Resolution    `json:"resolution"`
+	Source        string        `json:"source,omitempty"`
 }